@@ -3,8 +3,11 @@ module scrapper-assignment
 go 1.25.5
 
 require (
+	github.com/andybalholm/brotli v1.2.5
 	github.com/chromedp/cdproto v0.0.0-20250803210736-d308e07a266d
 	github.com/chromedp/chromedp v0.14.2
+	github.com/mattn/go-sqlite3 v1.14.52
+	golang.org/x/net v0.47.0
 )
 
 require (
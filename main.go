@@ -2,226 +2,542 @@ package main
 
 import (
 	"context"
-	"encoding/json" // for unmarshal problems
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/url"
-	"os" // for save file operations
+	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"strings" // String operations is able to record link hrefs
-	"time"    // need to set timeout
+	"strings"
+	"syscall"
+	"time"
 
-	// for network conditions and http code
-	"github.com/chromedp/cdproto/network"
-	"github.com/chromedp/chromedp"
+	"scrapper-assignment/scraper"
 )
 
-func main() {
-	// URL check
-	if len(os.Args) < 2 {
-		log.Fatal("Please provide a URL as a command-line argument.")
+// Fill the scraper options from the command line, bad values exit here.
+// urlFile is the -url-file path, read later with the other input files;
+// metricsFile is where -metrics-file writes once the batch is done.
+func parseFlags() (o *scraper.Options, urlFile, metricsFile string) {
+	o = &scraper.Options{}
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage: %s [flags] URL...\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintln(w, "\nExit codes: 0 success, 1 error, 3 HTTP 4xx, 4 HTTP 5xx, 5 navigation failure or timeout,")
+		fmt.Fprintln(w, "6 the page changed since the -compare run (only when nothing else failed), 130 interrupted")
 	}
-	rawURL := os.Args[1]
-	fmt.Printf("Navigating to URL: %s\n", rawURL)
+	verbose := flag.Bool("verbose", false, "also print chromedp protocol messages and step details")
+	quiet := flag.Bool("quiet", false, "only print errors")
+	flag.StringVar(&o.Out, "out", "scraped_data", "base output directory for run folders")
+	flag.BoolVar(&o.Flat, "flat", false, "write files straight into -out instead of a <timestamp>_<host> subfolder; later runs overwrite them")
+	// Invalid durations are rejected by the flag package before any browser starts
+	flag.DurationVar(&o.Timeout, "timeout", 120*time.Second, "how long a page may take to load and be captured, e.g. 45s or 2m")
+	var insecureHosts string
+	flag.StringVar(&insecureHosts, "insecure-hosts", "",
+		"comma separated hosts (or *.suffix) allowed to use invalid TLS certificates; TLS is enforced for other start URLs "+
+			"and redirect targets, resources an allowed page loads from other hosts are not checked")
+	flag.BoolVar(&o.AllowInsecureLocalhost, "allow-insecure-localhost", false,
+		"accept self-signed certificates on localhost only and enforce TLS elsewhere")
+	flag.BoolVar(&o.Noscript, "noscript", false, "save the contents of all <noscript> elements to noscript.html")
+	flag.StringVar(&o.TagRules, "tag-rules", "", "JSON file mapping tag names to keyword/selector rules, matched tags go to the manifest")
+	flag.BoolVar(&o.CompactManifest, "compact-manifest", false,
+		"write a single-line manifest (url, status, links_count, changed) and append it to index.ndjson in the output directory")
+	flag.BoolVar(&o.Breadcrumbs, "breadcrumbs", false, "save the breadcrumb trail (JSON-LD or markup) to breadcrumbs.json")
+	flag.BoolVar(&o.StripDataURIs, "strip-data-uris", false,
+		"replace data: URIs in img src/srcset and CSS url() with a placeholder before saving page.html")
+	flag.BoolVar(&o.SaveDataURIs, "save-data-uris", false, "with -strip-data-uris, also save the stripped data into data_uris/")
+	flag.BoolVar(&o.WaitIdle, "wait-idle", false, "wait until the network goes quiet before capture, like Puppeteer's networkidle0/2")
+	flag.IntVar(&o.WaitIdleRequests, "wait-idle-requests", 0, "requests allowed to stay open for -wait-idle, 2 tolerates analytics beacons and long polling")
+	flag.DurationVar(&o.WaitIdleQuiet, "wait-idle-quiet", 500*time.Millisecond, "how long the network must stay quiet for -wait-idle")
+	flag.DurationVar(&o.WaitIdleTimeout, "wait-idle-timeout", 30*time.Second, "how long -wait-idle waits before capturing anyway")
+	flag.StringVar(&o.WaitGone, "wait-gone", "", "wait until no visible element matches this CSS selector (e.g. a spinner) before capture")
+	flag.DurationVar(&o.WaitGoneTimeout, "wait-gone-timeout", 30*time.Second, "how long -wait-gone waits before capturing anyway")
+	flag.StringVar(&o.WaitFor, "wait-for", "",
+		"wait until an element matching this CSS selector is visible before capture; the page fails if it never appears")
+	flag.DurationVar(&o.WaitForTimeout, "wait-for-timeout", 30*time.Second, "how long -wait-for waits for the selector")
+	var waitCountValue string
+	var maxFileSize string
+	flag.StringVar(&maxFileSize, "max-filesize", "",
+		"skip saving page.html, a screenshot, page.pdf or an image larger than this, in bytes or with KB, MB or GB (e.g. 10MB)")
+	flag.StringVar(&waitCountValue, "wait-count", "", "selector:N, wait until at least N elements match the selector before capture")
+	flag.DurationVar(&o.WaitCountTimeout, "wait-count-timeout", 30*time.Second, "how long -wait-count waits before capturing anyway")
+	flag.BoolVar(&o.DOMStats, "dom-stats", false, "record DOM node count, max depth and common tag counts in the manifest")
+	flag.BoolVar(&o.DOMJSON, "dom-json", false,
+		fmt.Sprintf("save the rendered DOM as a JSON tree (tag, attrs, text, children) to dom.json, capped at depth %d and %d nodes",
+			scraper.DOMJSONMaxDepth, scraper.DOMJSONMaxNodes))
+	flag.BoolVar(&o.ExtractPrices, "extract-prices", false,
+		"save prices and currencies to prices.json; JSON-LD, microdata and meta tags are preferred, "+
+			"visible text is only pattern matched when they have none, so results are heuristic")
+	flag.BoolVar(&o.StructuredData, "structured-data", false,
+		"save every JSON-LD block and microdata item to structured_data.json; blocks that are not valid JSON are skipped")
+	flag.StringVar(&o.Frame, "frame", "",
+		"CSS selector or URL prefix of a same-origin iframe; page.html and links.txt are taken from inside it")
+	flag.StringVar(&o.Selector, "selector", "", "CSS selector of one element; page.html holds only its outer HTML instead of the whole page")
+	flag.StringVar(&o.Template, "template", "",
+		"Go text/template file executed with the run result (.URL, .StatusCode, .Title, .Links, .Metadata, .Text, .Manifest), saved as report.<ext>")
+	flag.StringVar(&o.TemplateExt, "template-ext", "txt", "file extension of the -template output, e.g. md or csv")
+	flag.BoolVar(&o.PDF, "pdf", false, "also save the rendered page as an A4 portrait page.pdf with background colors")
+	flag.BoolVar(&o.CaptureConsole, "capture-console", false,
+		"save console messages ([log], [warn], [error], ...) and uncaught JavaScript errors to console.log")
+	flag.BoolVar(&o.CheckLinks, "check-links", false,
+		"request every extracted link (HEAD, then GET) and save url,status to link_status.csv; -delay applies per host")
+	flag.StringVar(&o.LinksFormat, "links-format", "txt",
+		"how the links file is saved: txt (one URL per line), json (url and anchor text) or csv (url,text,internal)")
+	flag.BoolVar(&o.PseudoLinks, "pseudo-links", false, "save javascript:, mailto: and tel: links to pseudo_links.txt instead of just leaving them out")
+	flag.BoolVar(&o.KeepFragments, "keep-fragments", false, "keep #fragments in links instead of merging page.html#a and page.html#b into one link")
+	flag.BoolVar(&o.StripTrailingSlash, "strip-trailing-slash", false, "treat /path/ and /path as the same link")
+	flag.BoolVar(&o.IncludeSubdomains, "include-subdomains", false,
+		"count links to subdomains of the page host as internal in links_internal.txt")
+	flag.StringVar(&o.Proxy, "proxy", "",
+		"http://, https:// or socks5:// proxy for the browser; an unreachable proxy fails the navigation")
+	var proxyAuth, basicAuth string
+	flag.StringVar(&basicAuth, "basic-auth", "", "user:pass for pages behind HTTP Basic authentication, only sent to the scraped page's host")
+	flag.StringVar(&proxyAuth, "proxy-auth", "", "user:pass for a proxy that asks for authentication (http and https proxies only)")
+	flag.StringVar(&o.UserDataDir, "user-data-dir", "",
+		"Chrome profile directory kept between runs, so logins made once with -headful are reused; "+
+			"never share it between runs at the same time (default: a fresh temporary profile)")
+	flag.StringVar(&o.ChromePath, "chrome-path", "", "Chrome or Chromium executable to use (default: google-chrome, chromium and the usual install locations)")
+	flag.BoolVar(&o.Headful, "headful", false, "show the browser window while scraping, for debugging rendering issues")
+	flag.BoolVar(&o.IgnoreRobots, "ignore-robots", false, "do not check robots.txt before scraping, e.g. for your own site")
+	flag.IntVar(&o.Depth, "depth", 0,
+		"follow same-host links breadth-first up to this many levels from each start URL (0 = no crawling); "+
+			"every link found goes to <out>/all_links.txt")
+	flag.IntVar(&o.MaxPages, "max-pages", 100,
+		"stop queueing once this many pages (start URLs included) are scheduled with -depth (0 = no limit); "+
+			"URL lists without -depth are only cut off when it is given explicitly")
+	flag.StringVar(&o.Resume, "resume", "",
+		"continue an interrupted crawl from its crawl_state.json, pages whose files are still there are skipped; "+
+			"crawls of more than one page save <out>/crawl_state.json as they go")
+	flag.StringVar(&urlFile, "url-file", "", "text file with one URL per line to scrape after the command-line URLs; blank lines and # comments are ignored")
+	flag.StringVar(&metricsFile, "metrics-file", "",
+		"after the run, update Prometheus text format metrics (pages, links, bytes, HTTP errors, duration) in this file, e.g. for node_exporter's textfile collector; counters add up across runs")
+	flag.IntVar(&o.Concurrency, "concurrency", 1,
+		"scrape this many pages at once, each in its own tab of one shared browser")
+	flag.BoolVar(&o.AdaptiveConcurrency, "adaptive-concurrency", false,
+		"halve the pages loading at once when one answers 429 or times out, and step back up towards -concurrency as pages succeed")
+	flag.IntVar(&o.MaxFileDescriptors, "max-file-descriptors", 0,
+		fmt.Sprintf("lower -concurrency to fit this many open files, about %d per page (0 = the ulimit -n soft limit, -1 = no check)",
+			scraper.FileDescriptorsPerPage))
+	flag.IntVar(&o.AdaptiveMinConcurrency, "adaptive-min-concurrency", 1, "with -adaptive-concurrency, never go below this many pages at once")
+	flag.DurationVar(&o.Delay, "delay", 0,
+		"wait at least this long between two page loads on the same host, e.g. 2s; other hosts are not held up (0 = no delay)")
+	flag.IntVar(&o.Retries, "retries", 2,
+		"retry the page up to this many times with exponential backoff on 5xx, timeouts and network errors (never on 4xx)")
+	flag.StringVar(&o.Cookies, "cookies", "",
+		"JSON file with an array of cookies (name, value, domain, optional path) set before navigating, for logged-in pages")
+	flag.BoolVar(&o.IsolateCookies, "isolate-cookies", false,
+		"don't carry cookies from one page of a host to the next; by default sessions and dismissed consent banners persist across a crawl")
+	flag.BoolVar(&o.Text, "text", false, "save the visible page text, with whitespace collapsed, to text.txt")
+	flag.StringVar(&o.Compare, "compare", "",
+		"earlier run folder to compare the page text with; a change is saved as diff.txt and exits with code 6")
+	flag.StringVar(&o.DiffMode, "diff-mode", "text",
+		"what -compare diffs: text (the visible text, ignoring markup churn) or html (page.html, template changes included)")
+	flag.BoolVar(&o.SelfContained, "self-contained", false,
+		"also save offline.html with stylesheets, images and fonts downloaded into assets/ and scripts removed, viewable without the site (slower)")
+	flag.BoolVar(&o.DownloadImages, "download-images", false,
+		"download every <img> src and srcset image into images/; runs into the same folder (-flat, -no-timestamp-folder) "+
+			"keep images the server reports unchanged by ETag or Last-Modified")
+	flag.IntVar(&o.DownloadConcurrency, "download-concurrency", 4,
+		"how many images and -self-contained assets download at once, across all pages of the run")
+	flag.StringVar(&o.UserAgent, "user-agent", "",
+		"user agent for the browser and robots.txt matching, e.g. a bot name with contact URL or a mobile browser string")
+	flag.IntVar(&o.Width, "width", 1920, "browser window width in pixels; -device replaces the viewport it emulates")
+	flag.IntVar(&o.Height, "height", 1080, "browser window height in pixels")
+	var deviceName string
+	flag.StringVar(&deviceName, "device", "",
+		"emulate a device's viewport, pixel ratio and user agent: iPhoneSE, iPhone12, iPhone14ProMax, Pixel5, GalaxyS20 or iPadAir")
+	flag.BoolVar(&o.AutoHeadfulFallback, "auto-headful-fallback", false,
+		"when the headless page has almost no text, reload it once in a visible browser")
+	flag.BoolVar(&o.Pagination, "pagination", false, "save pagination state (current/total pages, next/prev URLs) to pagination.json")
+	flag.StringVar(&o.ScreenshotMode, "screenshot-mode", "full", "the screenshot covers the full page (full) or only the visible window (viewport)")
+	flag.StringVar(&o.ScreenshotFormat, "screenshot-format", "png", "png for a lossless screenshot.png, jpeg for a much smaller screenshot.jpg at -screenshot-quality")
+	flag.IntVar(&o.ScreenshotQuality, "screenshot-quality", 90, "JPEG quality 0-100 with -screenshot-format jpeg")
+	flag.StringVar(&o.ScreenshotSelector, "screenshot-selector", "", "also capture just the first element matching this CSS selector into element.png")
+	flag.DurationVar(&o.ScreenshotTimeout, "screenshot-timeout", 30*time.Second,
+		"how long the screenshot may take before it is skipped and the rest of the page is still saved (0 for no separate limit)")
+	flag.IntVar(&o.MaxConcurrentScreenshots, "max-concurrent-screenshots", 0,
+		"limit how many screenshots and PDFs are captured at once, independent of how many pages load in parallel (0 = no limit)")
+	flag.BoolVar(&o.PierceShadow, "pierce-shadow", false,
+		"also extract from open shadow roots of web components (closed shadow roots stay inaccessible)")
+	flag.StringVar(&o.SQLite, "sqlite", "", "upsert url, status, title, text and link count into this SQLite database (needs a build with cgo)")
+	flag.BoolVar(&o.NoFiles, "no-files", false, "do not write the run folder (useful together with -sqlite)")
+	flag.BoolVar(&o.NoTimestampFolder, "no-timestamp-folder", false,
+		"write each host to <out>/<host>/ and replace the previous run's files there, files the run doesn't write are removed; "+
+			"-compare <out>/<host> still compares with the previous run, it is read before anything is overwritten")
+	flag.BoolVar(&o.GroupByHost, "group-by-host", false, "nest runs under <out>/<host>/<timestamp>/ instead of <out>/<timestamp>_<host>")
+	var dirPerm, filePerm string
+	flag.StringVar(&dirPerm, "dir-perm", "0755", "octal permission for created folders, before the umask")
+	flag.StringVar(&filePerm, "file-perm", "0644", "octal permission for created files, before the umask")
+	flag.StringVar(&o.NameTemplate, "name-template", "",
+		"Go template for the run folder name with {{.Host}}, {{.Timestamp}} and {{.URLHash}}, e.g. {{.Host}}/{{.URLHash}}; "+
+			"default <timestamp>_<host>")
+	flag.StringVar(&o.HTMLFormat, "html-format", "raw", "how page.html is saved: raw, pretty (reindented) or minify")
+	flag.BoolVar(&o.RawHTML, "raw-html", false,
+		"also save the HTML as the server sent it, before JavaScript ran, to raw.html; page.html stays the rendered DOM")
+	flag.BoolVar(&o.Readability, "readability", false, "isolate the main article content into article.html and article.txt")
+	flag.BoolVar(&o.RetryDifferentUA, "retry-different-ua", false,
+		"when the page answers 403 or 429, retry with the next built-in user agent")
+	flag.BoolVar(&o.StructuredText, "structured-text", false,
+		"save the visible text as headings, paragraphs and lists in reading order to structured_text.json")
+	flag.BoolVar(&o.FollowNewTargets, "follow-new-targets", false,
+		"also scrape tabs opened by the page into new_targets/<n>/")
+	flag.BoolVar(&o.Forms, "forms", false, "save each form's action, method and fields (name, type, required; never values) to forms.json")
+	flag.BoolVar(&o.Hreflang, "hreflang", false, "save the canonical URL and hreflang alternates to hreflang.json")
+	flag.StringVar(&o.EvalAfter, "eval-after", "", "JavaScript file to run in the page after navigation and before capture")
+	flag.DurationVar(&o.EvalAfterWait, "eval-after-wait", time.Second, "how long to wait after the -eval-after script")
+	flag.BoolVar(&o.BypassServiceWorker, "bypass-service-worker", false,
+		"send every request to the network instead of a service worker cache; use it for fresh PWA content, "+
+			"leave it off to capture what a returning visitor would see")
+	flag.BoolVar(&o.RunIDInName, "run-id-in-name", false, "append the run ID to the run folder name")
+	flag.IntVar(&o.MinTextLength, "min-text-length", 0,
+		"skip saving pages whose visible text is shorter than this; they are still recorded in the manifest")
+	flag.IntVar(&o.SlowRequests, "slow-requests", 0, "save the N slowest requests (url, type, duration) to slow_requests.json")
+	flag.BoolVar(&o.CheckOnly, "check-only", false,
+		"only load each page and report its status, without creating the run folder; the exit code still follows the status")
+	flag.BoolVar(&o.StaticLinks, "static-links", false,
+		"fetch the page with plain HTTP and write links.txt and metadata.json without a browser; falls back to the browser for JavaScript pages")
+	flag.BoolVar(&o.AutoScroll, "auto-scroll", false,
+		"scroll to the bottom repeatedly before capture until the page stops growing, for infinite scroll feeds")
+	flag.IntVar(&o.AutoScrollMax, "auto-scroll-max", 20, "with -auto-scroll, stop after this many scrolls")
+	flag.DurationVar(&o.AutoScrollPause, "auto-scroll-pause", time.Second, "with -auto-scroll, how long to wait for new content after each scroll")
+	flag.StringVar(&o.ScrollTo, "scroll-to", "", "CSS selector or Y pixel offset to scroll to, then save a viewport screenshot as scrolled.png")
+	flag.BoolVar(&o.AutoAcceptCookies, "auto-accept-cookies", false, "click the accept button of a cookie consent banner (OneTrust, Cookiebot, ... or a plain \"Accept\" button) before capture")
+	flag.BoolVar(&o.DetectGates, "detect-gates", false, "heuristically flag pages behind a login or paywall in the manifest")
+	flag.BoolVar(&o.ScreenshotOnError, "screenshot-on-error", false,
+		"when navigation or a wait fails, save whatever the page shows as error.png")
+	flag.BoolVar(&o.JSONOutput, "json", false,
+		"print status, title, links and the manifest as one JSON object per URL on stdout instead of writing files; logs go to stderr")
+	flag.BoolVar(&o.JSONScreenshot, "json-screenshot", false, "with -json, include the screenshot as base64")
+	flag.BoolVar(&o.JSONText, "json-text", false, "with -json, include the visible page text")
+	stdout := flag.Bool("stdout", false, "write nothing to disk and print everything as JSON on stdout: -json with -json-screenshot and -json-text")
+	var selectAll multiFlag
+	flag.Var(&selectAll, "select-all", "name=selector, save the text of every matching element to fields.json (repeatable)")
+	var hover multiFlag
+	flag.Var(&hover, "hover", "CSS selector to move the mouse over before capture, for hover menus and tooltips (repeatable)")
+	var headers multiFlag
+	flag.Var(&headers, "header", "\"Name: Value\" request header sent with the page and all its resources, e.g. Authorization (repeatable)")
+	var keyInputs multiFlag
+	flag.Var(&keyInputs, "key",
+		"selector:keys, focus the element and type keys before capture; {Enter}, {Tab}, {Escape} and arrows are special keys (repeatable)")
+	flag.Parse()
 
-	// Create files
-	parsedURL, err := url.Parse(rawURL)
-	if err != nil {
-		log.Fatal("Invalid URL: ", err)
-	}
-	hostname := parsedURL.Hostname()
-
-	// The time to be added for files name
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	folderPath := filepath.Join("scraped_data", fmt.Sprintf("%s_%s", timestamp, hostname))
-
-	// 0755 -> rwxr-xr-x
-	if err := os.MkdirAll(folderPath, 0755); err != nil {
-		log.Fatal("Failed to create directory: ", err)
-	}
-
-	fmt.Print("The Registry folder is created.", folderPath)
-
-	/*
-		// Create context
-		// := -> Short variable declaration -> makes both variable and assigns value
-		ctx, cancel := chromedp.NewContext(context.Background())
-		defer cancel() // Browser will be closed when main function exits
-
-		// Timer context
-		ctx, cancel = context.WithTimeout(ctx, 30*time.Second) // For Delay
-		defer cancel()
-	*/
-
-	// Custom options for allocator
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		// Robot-like behaviour is blocked by some websites
-		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, "+
-			"like Gecko) Chrome/120.0.0.0 Safari/537.36"),
-		chromedp.WindowSize(1920, 1080),
-		chromedp.Flag("ignore-certificate-errors", true),
-		chromedp.Flag("disable-http2", true),
-		// For testing, we can see the browser
-		//chromedp.Flag("headless", false), // Set to false to see the browser
-	)
-
-	// Setting up allocator context
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancelAlloc()
-
-	// Create context with the allocator
-	ctx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
-
-	// For secure browsing, set timeout
-	ctx, cancel = context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-
-	fmt.Printf("Targeting URL: %s\n", rawURL)
-
-	// Enable network events to capture status codes
-	var statusCode int64
-	var statusText string
-	chromedp.ListenTarget(ctx, func(ev interface{}) {
-		if ev, ok := ev.(*network.EventResponseReceived); ok {
-			// Just capture the main document response
-			if ev.Type == network.ResourceTypeDocument {
-				statusCode = ev.Response.Status
-				statusText = ev.Response.StatusText
-			}
-		}
-	})
-	err = chromedp.Run(ctx, chromedp.Navigate(rawURL))
-	// print network request status
-	listNetworkRequests(statusCode, statusText)
-	if err != nil {
-		log.Fatal("Failed to navigate: ", err)
+	if *stdout {
+		o.JSONOutput, o.JSONScreenshot, o.JSONText = true, true, true
 	}
 
-	// Navigate to the URL
-	err = chromedp.Run(ctx, chromedp.Navigate(rawURL))
-	// Handle error
-	if err != nil {
-		log.Fatal("Failed to navigate: ", err)
+	switch {
+	case *verbose && *quiet:
+		log.Fatal("-verbose and -quiet can't be used together")
+	case *verbose:
+		o.LogLevel = scraper.LevelDebug
+	case *quiet:
+		o.LogLevel = scraper.LevelError
 	}
-
-	// Run content retrieval
-	htmlData, err := contentRetrieval(ctx)
-
-	// Get html content
-	if err != nil {
-		log.Println("Failed to retrieve content: ", err)
-	} else {
-		// Save html within the folder
-		savePath := filepath.Join(folderPath, "page.html")
-		if err := os.WriteFile(savePath, []byte(htmlData), 0644); err != nil {
-			log.Println("Failed to save HTML file: ", err)
-		} else {
-			fmt.Printf("HTML content saved to %s\n", savePath)
+	o.Out = filepath.Clean(o.Out)
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	// A blank -user-agent would quietly fall back to the built-in user
+	// agents, so refuse it instead
+	if explicit["user-agent"] && strings.TrimSpace(o.UserAgent) == "" {
+		log.Fatal("-user-agent must not be empty")
+	}
+	// The default limit is there for crawls, a plain URL list is scraped
+	// in full unless -max-pages says otherwise
+	if o.Depth == 0 && !explicit["max-pages"] {
+		o.MaxPages = 0
+	}
+	o.UserAgent = strings.TrimSpace(o.UserAgent)
+	if deviceName != "" {
+		d, err := scraper.LookupDevice(deviceName)
+		if err != nil {
+			log.Fatal(err)
 		}
+		o.Device = &d
 	}
-
-	imgData, err := captureScreenshot(ctx)
-	if err != nil {
-		log.Println("Image fault: ", err)
-	} else {
-		// Save screenshot within the folder
-		savepath := filepath.Join(folderPath, "screenshot.png")
-		if err := os.WriteFile(savepath, imgData, 0644); err != nil {
-			log.Println("Failed to save screenshot: ", err)
-		} else {
-			fmt.Printf("Screenshot saved to %s\n", savepath)
+	if o.WaitIdle && (o.WaitIdleRequests < 0 || o.WaitIdleQuiet <= 0 || o.WaitIdleTimeout <= 0) {
+		log.Fatal("-wait-idle-requests can't be negative, -wait-idle-quiet and -wait-idle-timeout must be positive")
+	}
+	if o.Flat && o.NoTimestampFolder {
+		log.Fatal("-flat and -no-timestamp-folder can't be combined, pick one output layout")
+	}
+	if o.NameTemplate != "" && (o.Flat || o.NoTimestampFolder || o.GroupByHost) {
+		log.Fatal("-name-template can't be combined with -flat, -no-timestamp-folder or -group-by-host, pick one output layout")
+	}
+	if o.NoTimestampFolder && o.Depth > 0 {
+		log.Println("With -no-timestamp-folder every page of a host is written to the same folder, later pages overwrite earlier ones")
+	}
+	if o.Flat && (flag.NArg() > 1 || urlFile != "" || o.Depth > 0) {
+		log.Println("With -flat every page is written to the same folder, later pages overwrite earlier ones")
+	}
+	if o.AutoScroll && (o.AutoScrollMax < 1 || o.AutoScrollPause <= 0) {
+		log.Fatal("-auto-scroll-max must be at least 1 and -auto-scroll-pause positive")
+	}
+	if o.Concurrency < 1 {
+		log.Fatalf("Invalid -concurrency %d, it must be at least 1", o.Concurrency)
+	}
+	if o.AdaptiveConcurrency && (o.AdaptiveMinConcurrency < 1 || o.AdaptiveMinConcurrency > o.Concurrency) {
+		log.Fatalf("Invalid -adaptive-min-concurrency %d, it must be between 1 and -concurrency %d", o.AdaptiveMinConcurrency, o.Concurrency)
+	}
+	if o.DownloadConcurrency < 1 {
+		log.Fatalf("Invalid -download-concurrency %d, it must be at least 1", o.DownloadConcurrency)
+	}
+	if o.UserDataDir != "" {
+		if o.Concurrency > 1 {
+			log.Fatal("-user-data-dir can't be used with -concurrency above 1, Chrome locks the profile")
+		}
+		dir, err := filepath.Abs(o.UserDataDir)
+		if err != nil {
+			log.Fatal(err)
 		}
+		o.UserDataDir = dir
 	}
-
-	links, err := extractLinks(ctx)
+	if o.ChromePath != "" {
+		// Checked here, a bad path would otherwise fail every page the same way
+		path, err := exec.LookPath(o.ChromePath)
+		if err != nil {
+			log.Fatalf("Invalid -chrome-path: %v", err)
+		}
+		o.ChromePath = path
+	}
+	if o.Width <= 0 || o.Height <= 0 {
+		log.Fatalf("Invalid window size %dx%d, -width and -height must be positive", o.Width, o.Height)
+	}
+	if o.Delay < 0 {
+		log.Fatalf("Invalid -delay %s, it must not be negative", o.Delay)
+	}
+	if o.Timeout <= 0 {
+		log.Fatalf("Invalid -timeout %s, it must be positive", o.Timeout)
+	}
+	o.InsecureHosts = scraper.SplitList(insecureHosts)
+	if o.Proxy != "" {
+		if err := scraper.ValidateProxy(o.Proxy); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if basicAuth != "" {
+		var err error
+		if o.BasicAuthUser, o.BasicAuthPass, err = scraper.ParseBasicAuth(basicAuth); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if proxyAuth != "" {
+		var err error
+		if o.ProxyUser, o.ProxyPass, err = scraper.ParseProxyAuth(proxyAuth); err != nil {
+			log.Fatal(err)
+		}
+		if strings.HasPrefix(o.Proxy, "socks5://") {
+			log.Println("Chrome does not authenticate to SOCKS proxies, -proxy-auth will likely be ignored")
+		}
+	}
+	switch o.HTMLFormat {
+	case "raw", "pretty", "minify":
+	default:
+		log.Fatalf("Invalid -html-format %q, expected raw, pretty or minify", o.HTMLFormat)
+	}
+	if o.Selector != "" && o.Frame != "" {
+		log.Fatal("-selector and -frame cannot be used together")
+	}
+	switch o.ScreenshotMode {
+	case "full", "viewport":
+	default:
+		log.Fatalf("Invalid -screenshot-mode %q, expected full or viewport", o.ScreenshotMode)
+	}
+	if o.ScreenshotTimeout < 0 {
+		log.Fatalf("Invalid -screenshot-timeout %s, it can't be negative", o.ScreenshotTimeout)
+	}
+	switch o.ScreenshotFormat {
+	case "png", "jpeg":
+	default:
+		log.Fatalf("Invalid -screenshot-format %q, expected png or jpeg", o.ScreenshotFormat)
+	}
+	if o.ScreenshotQuality < 0 || o.ScreenshotQuality > 100 {
+		log.Fatalf("Invalid -screenshot-quality %d, it must be between 0 and 100", o.ScreenshotQuality)
+	}
+	switch o.LinksFormat {
+	case "txt", "json", "csv":
+	default:
+		log.Fatalf("Invalid -links-format %q, expected txt, json or csv", o.LinksFormat)
+	}
+	switch o.DiffMode {
+	case "text", "html":
+	default:
+		log.Fatalf("Invalid -diff-mode %q, expected text or html", o.DiffMode)
+	}
+	fields, err := scraper.ParseFieldSelectors(selectAll)
 	if err != nil {
-		log.Println("Failed to extract links: ", err)
-	} else {
-		// Save links within the folder
-		savepath := filepath.Join(folderPath, "links.txt")
-		linksContent := strings.Join(links, "\n")
-		if err := os.WriteFile(savepath, []byte(linksContent), 0644); err != nil {
-			log.Println("Failed to save links: ", err)
-		} else {
-			fmt.Printf("Links saved to %d links in %s\n", len(links), savepath)
+		log.Fatal(err)
+	}
+	o.SelectAll = fields
+	if waitCountValue != "" {
+		o.WaitCountSelector, o.WaitCountN, err = scraper.ParseWaitCount(waitCountValue)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
+	if maxFileSize != "" {
+		if o.MaxFileSize, err = scraper.ParseByteSize(maxFileSize); err != nil {
+			log.Fatalf("Invalid -max-filesize: %v", err)
+		}
+	}
+	if o.DirPerm, err = scraper.ParsePerm(dirPerm); err != nil {
+		log.Fatalf("Invalid -dir-perm: %v", err)
+	}
+	// Without write and search on the folder nothing could be saved in it
+	if o.DirPerm&0300 != 0300 {
+		log.Fatalf("Invalid -dir-perm %s: the owner needs write and execute (0300)", dirPerm)
+	}
+	if o.FilePerm, err = scraper.ParsePerm(filePerm); err != nil {
+		log.Fatalf("Invalid -file-perm: %v", err)
+	}
+	o.Headers, err = scraper.ParseHeaders(headers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	o.Hover = hover
+	o.KeyInputs, err = scraper.ParseKeyInputs(keyInputs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return o, urlFile, metricsFile
 }
 
-func contentRetrieval(ctx context.Context) (string, error) {
-	var htmlContent string
+// Flag that can be given several times
+type multiFlag []string
 
-	// Get everything tagged with <html>
-	err := chromedp.Run(ctx, chromedp.OuterHTML("html", &htmlContent))
-	// Handle error
+func (m *multiFlag) String() string {
+	return strings.Join(*m, ", ")
+}
+
+func (m *multiFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+func main() {
+	code, err := run()
 	if err != nil {
-		log.Printf("Error retrieving content: %v", err)
+		log.Println(err)
 	}
-
-	return htmlContent, err
+	os.Exit(code)
 }
 
-func captureScreenshot(ctx context.Context) ([]byte, error) {
-	// The image is formed using zeros and ones.
-	var screenShotBuffer []byte
+// Process exit codes, so scripts can tell why a scrape failed. With several
+// pages the highest code wins.
+const (
+	exitOK         = 0
+	exitError      = 1   // bad flags, unreadable input files, other failures
+	exitHTTPClient = 3   // the page answered 4xx
+	exitHTTPServer = 4   // the page answered 5xx
+	exitNavigation = 5   // the page could not be loaded or timed out
+	exitChanged    = 6   // -compare found a change, reported only when nothing failed
+	exitInterrupt  = 130 // stopped by SIGINT or SIGTERM, like a shell reports Ctrl-C
+)
+
+// Everything main does; the returned code becomes the exit status
+func run() (int, error) {
+	start := time.Now()
+	o, urlFile, metricsFile := parseFlags()
 
-	// Take full page ss
-	// Picture quality 0 - 100, we set to 90
-	err := chromedp.Run(ctx, chromedp.FullScreenshot(&screenShotBuffer, 90))
+	// Keep stdout for the JSON document, progress output goes to stderr
+	o.JSONWriter = os.Stdout
+	o.LogOutput = os.Stderr
+
+	urls := flag.Args()
+	if urlFile != "" {
+		fileURLs, err := readURLFile(urlFile)
+		if err != nil {
+			return exitError, fmt.Errorf("failed to read URL file: %v", err)
+		}
+		urls = append(urls, fileURLs...)
+	}
 
-	// Handle error
+	// URL check, a resumed crawl brings its own
+	if len(urls) < 1 && o.Resume == "" {
+		return exitError, errors.New("please provide at least one URL as a command-line argument or with -url-file, or -resume a crawl")
+	}
+
+	s, err := scraper.New(*o)
 	if err != nil {
-		log.Printf("Error capturing screenshot: %v", err)
+		return exitError, err
 	}
+	defer s.Close()
 
-	return screenShotBuffer, err
-}
+	// Ctrl-C cancels the pages in flight so their browsers close and their
+	// manifests are written; a second Ctrl-C kills the process right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-func extractLinks(ctx context.Context) ([]string, error) {
-	var jsonResult string
-	// JavaScript to extract all href attributes from <a> tags
-	// a little vast because sometimes href is object for SVG links
-	javascript := `JSON.stringify(Array.from(document.querySelectorAll('a')).map(a => {
-		if (typeof a.href === 'object' && a.href !== null) {
-			return a.href.baseVal; // SVG linkleri için
+	summaries := s.Crawl(ctx, urls)
+
+	s.Infof("Summary:")
+	code := exitOK
+	ok, failed, skipped := 0, 0, 0
+	changed := false
+	for _, summary := range summaries {
+		s.Infof("%s", summary)
+		code = max(code, exitCode(summary))
+		changed = changed || summary.Changed
+		switch {
+		case summary.Err != nil:
+			failed++
+		case summary.Skipped != "":
+			skipped++
+		default:
+			ok++
 		}
-		return a.href; // Normal linkler için
-	}).filter(href => typeof href === 'string' && href !== ""))`
-	// Evaluate the JavaScript in the page context
-	err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &jsonResult))
-	if err != nil {
-		return nil, fmt.Errorf("error extracting links: %v", err)
 	}
-	//unpack the JSON string into a Go slice
-	var links []string
-	err = json.Unmarshal([]byte(jsonResult), &links)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling JSON: %v", err)
+	s.Infof("%d pages: %d ok, %d failed, %d skipped", len(summaries), ok, failed, skipped)
+	if metricsFile != "" {
+		if err := scraper.WriteMetrics(metricsFile, summaries, time.Since(start)); err != nil {
+			s.Printf("Failed to write metrics: %v\n", err)
+		} else {
+			s.Infof("Metrics saved to %s", metricsFile)
+		}
 	}
-	return links, nil
+	if code == exitOK && changed {
+		code = exitChanged
+	}
+	if ctx.Err() != nil {
+		return exitInterrupt, errors.New("interrupted, results are partial")
+	}
+	return code, nil
 }
 
-// Network request status code analysis
-func listNetworkRequests(code int64, text string) {
-	if code == 0 {
-		return
+// One URL per line; blank lines and # comments are skipped
+func readURLFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("Request network: %d (%s)\n", code, text)
+	var urls []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, nil
+}
+
+// Exit status for one page of the batch
+func exitCode(s scraper.PageSummary) int {
 	switch {
-	case code >= 200 && code < 300:
-		fmt.Println("Request SUCCESSFUL: Site is accessible.")
-	case code >= 300 && code < 400:
-		fmt.Printf("Request REDIRECTION (%d): Site is redirecting to another address.\n", code)
-	case code == 403:
-		log.Println("Request FORBIDDEN (403): Access denied (WAF or Bot Protection).")
-	case code == 404:
-		log.Println("Request NOT FOUND (404): Page does not exist.")
-	case code >= 400 && code < 500:
-		log.Printf("Request CLIENT ERROR (%d): %s\n", code, text)
-	case code >= 500:
-		log.Printf("Request SERVER ERROR (%d): Target site is down or faulty.\n", code)
-	default:
-		log.Printf("Request UNKNOWN STATUS: %d\n", code)
+	case errors.Is(s.Err, scraper.ErrNavigation), errors.Is(s.Err, context.DeadlineExceeded):
+		return exitNavigation
+	case s.Err != nil:
+		return exitError
+	case s.StatusCode >= 500:
+		return exitHTTPServer
+	case s.StatusCode >= 400:
+		return exitHTTPClient
 	}
+	return exitOK
 }
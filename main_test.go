@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"scrapper-assignment/scraper"
+)
+
+// parseFlags can only run once per process, flags register on the global set
+func TestURLListWithoutDepthIsNotCapped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><body><p>Page %s</p><a href="/next">Next</a></body></html>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	args := []string{"scrapper", "-static-links", "-ignore-robots", "-quiet", "-out", t.TempDir()}
+	for i := range 101 {
+		args = append(args, fmt.Sprintf("%s/page/%d", srv.URL, i))
+	}
+	defer func(saved []string) { os.Args = saved }(os.Args)
+	os.Args = args
+
+	o, _, _ := parseFlags()
+	o.LogOutput = io.Discard
+	s, err := scraper.New(*o)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	summaries := s.Crawl(context.Background(), flag.Args())
+	scraped := 0
+	for _, summary := range summaries {
+		if summary.Err != nil || summary.Skipped != "" {
+			t.Errorf("%s", summary)
+			continue
+		}
+		scraped++
+	}
+	if scraped != 101 {
+		t.Errorf("scraped %d of 101 pages", scraped)
+	}
+}
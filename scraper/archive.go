@@ -0,0 +1,320 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Stylesheets importing stylesheets are followed this many levels deep,
+// deeper ones keep pointing at the site
+const archiveCSSDepth = 3
+
+// url(...) and @import "..." references in CSS
+var cssRefPattern = regexp.MustCompile(`(?i)url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)|@import\s+(['"])([^'"]+)(['"])`)
+
+// Downloads the assets of one page for offline.html. References are
+// collected in a first pass and rewritten in a second, so anything that
+// failed to download keeps its original URL instead of a broken local one.
+type archiver struct {
+	log       *logger
+	client    *http.Client
+	slots     semaphore
+	index     *assetIndex
+	userAgent string
+	limit     int64
+
+	names map[string]string // asset URL -> file name in assets/
+	used  map[string]bool
+	css   map[string]bool // URLs referenced as stylesheets
+	data  map[string][]byte
+}
+
+// Save offline.html, the page with its stylesheets, images and fonts
+// downloaded into assets/ and scripts removed; the DOM is already rendered
+// and scripts would only try to reach the site again. Returns how many
+// assets were saved.
+func saveOffline(ctx context.Context, lg *logger, client *http.Client, slots semaphore, out *outputDir, pageHTML, baseURL, userAgent string) (int, error) {
+	doc, err := html.Parse(strings.NewReader(pageHTML))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing HTML: %v", err)
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return 0, err
+	}
+	a := &archiver{
+		log:       lg,
+		client:    client,
+		slots:     slots,
+		index:     loadAssetIndex(out, "assets"),
+		userAgent: userAgent,
+		limit:     maxImageBytes,
+		names:     make(map[string]string),
+		used:      make(map[string]bool),
+		css:       make(map[string]bool),
+		data:      make(map[string][]byte),
+	}
+	if out.maxFileSize > 0 && out.maxFileSize < a.limit {
+		a.limit = out.maxFileSize
+	}
+
+	base = documentBase(doc, base)
+	pending := a.collect(func(ref refFunc) { rewriteHTMLRefs(doc, base, ref) })
+	for depth := 0; len(pending) > 0; depth++ {
+		if err := a.download(ctx, pending); err != nil {
+			lg.Printf("Stopped downloading assets: %v\n", err)
+			break
+		}
+		if depth == archiveCSSDepth {
+			break
+		}
+		// Stylesheets pull in fonts, background images and more CSS
+		var next []string
+		for _, u := range pending {
+			if data, ok := a.data[u]; ok && a.css[u] {
+				cssBase, _ := url.Parse(u)
+				next = append(next, a.collect(func(ref refFunc) { rewriteCSSRefs(string(data), cssBase, ref) })...)
+			}
+		}
+		pending = next
+	}
+
+	// Second pass: point at the files that made it, CSS files referring to
+	// their neighbours in the same folder
+	saved := 0
+	for u, data := range a.data {
+		if a.css[u] {
+			cssBase, _ := url.Parse(u)
+			data = []byte(rewriteCSSRefs(string(data), cssBase, a.localRef("")))
+		}
+		if _, err := out.writeCapped(path.Join("assets", a.names[u]), data); err != nil {
+			lg.Printf("Failed to save asset %s: %v\n", u, err)
+			delete(a.data, u)
+			continue
+		}
+		saved++
+	}
+	if err := a.index.save(); err != nil {
+		lg.Printf("Failed to save the asset index: %v\n", err)
+	}
+	rewriteHTMLRefs(doc, base, a.localRef("assets/"))
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return saved, err
+	}
+	_, err = out.writeCapped("offline.html", buf.Bytes())
+	return saved, err
+}
+
+// Called with every resolved asset reference and whether it is a
+// stylesheet; what it returns replaces the reference
+type refFunc func(abs string, css bool) string
+
+// Run walk with a ref that registers new URLs, return those URLs
+func (a *archiver) collect(walk func(refFunc)) []string {
+	var found []string
+	walk(func(abs string, css bool) string {
+		if css {
+			a.css[abs] = true
+		}
+		if _, ok := a.names[abs]; !ok {
+			a.names[abs] = a.fileName(abs)
+			found = append(found, abs)
+		}
+		return abs
+	})
+	return found
+}
+
+// Local path for downloaded assets, the original URL for the rest
+func (a *archiver) localRef(prefix string) refFunc {
+	return func(abs string, css bool) string {
+		if _, ok := a.data[abs]; ok {
+			return prefix + a.names[abs]
+		}
+		return abs
+	}
+}
+
+// Unique, filesystem safe name within assets/
+func (a *archiver) fileName(abs string) string {
+	name := imageFileNames([]string{abs})[0]
+	if a.used[name] {
+		ext := path.Ext(name)
+		name = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), len(a.used)+1, ext)
+	}
+	a.used[name] = true
+	return name
+}
+
+// Fetch urls as the download slots allow, failures are logged and left out.
+// Assets other than stylesheets are requested conditionally, an unchanged
+// one is read back from the earlier run's copy. Saved stylesheets point at
+// local files, so they are always downloaded again.
+func (a *archiver) download(ctx context.Context, urls []string) error {
+	var mu sync.Mutex
+	return fetchEach(ctx, a.slots, len(urls), func(i int) {
+		u := urls[i]
+		var prev assetEntry
+		if !a.css[u] {
+			prev = a.index.validators(u, a.names[u])
+		}
+		asset, err := fetchAsset(a.client, u, a.userAgent, a.limit, prev)
+		if err == nil && asset.NotModified {
+			asset.Data, err = os.ReadFile(filepath.Join(a.index.out.path, "assets", a.names[u]))
+		}
+		if err != nil {
+			a.log.Printf("Failed to download asset %s: %v\n", u, err)
+			return
+		}
+		if !a.css[u] {
+			a.index.record(u, a.names[u], asset)
+		}
+		mu.Lock()
+		a.data[u] = asset.Data
+		mu.Unlock()
+	})
+}
+
+// <base href> wins over the page URL, and is removed since the assets are
+// local afterwards
+func documentBase(doc *html.Node, base *url.URL) *url.URL {
+	var found *html.Node
+	walkElements(doc, func(n *html.Node) {
+		if n.Data == "base" && found == nil {
+			found = n
+		}
+	})
+	if found == nil {
+		return base
+	}
+	if href := attr(found, "href"); href != "" {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+	found.Parent.RemoveChild(found)
+	return base
+}
+
+// Rewrite the asset references of the document, and drop its scripts
+func rewriteHTMLRefs(doc *html.Node, base *url.URL, ref refFunc) {
+	resolve := func(value string, css bool) string {
+		u, err := base.Parse(strings.TrimSpace(value))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return value
+		}
+		u.Fragment = ""
+		return ref(u.String(), css)
+	}
+
+	var scripts []*html.Node
+	walkElements(doc, func(n *html.Node) {
+		switch n.Data {
+		case "script":
+			scripts = append(scripts, n)
+			return
+		case "style":
+			if c := n.FirstChild; c != nil && c.Type == html.TextNode {
+				c.Data = rewriteCSSRefs(c.Data, base, ref)
+			}
+		}
+		for i, a := range n.Attr {
+			switch {
+			case n.Data == "link" && a.Key == "href":
+				rel := strings.ToLower(attr(n, "rel"))
+				if strings.Contains(rel, "stylesheet") || strings.Contains(rel, "icon") {
+					n.Attr[i].Val = resolve(a.Val, strings.Contains(rel, "stylesheet"))
+				}
+			case a.Key == "src" && (n.Data == "img" || n.Data == "source" || n.Data == "video" || n.Data == "audio"),
+				a.Key == "poster" && n.Data == "video":
+				n.Attr[i].Val = resolve(a.Val, false)
+			case a.Key == "srcset" && (n.Data == "img" || n.Data == "source"):
+				n.Attr[i].Val = rewriteSrcset(a.Val, resolve)
+			case a.Key == "href" && (n.Data == "a" || n.Data == "area"):
+				// Links lead back to the site instead of missing local files
+				if u, err := base.Parse(strings.TrimSpace(a.Val)); err == nil && u.Scheme != "javascript" {
+					n.Attr[i].Val = u.String()
+				}
+			case a.Key == "style":
+				n.Attr[i].Val = rewriteCSSRefs(a.Val, base, ref)
+			}
+		}
+		// The rewritten files no longer match their hashes
+		removeAttr(n, "integrity")
+	})
+	for _, s := range scripts {
+		s.Parent.RemoveChild(s)
+	}
+}
+
+// Each candidate is "url descriptor"; data: URIs contain commas, so a
+// srcset with one is left alone
+func rewriteSrcset(srcset string, resolve func(string, bool) string) string {
+	if strings.Contains(srcset, "data:") {
+		return srcset
+	}
+	candidates := strings.Split(srcset, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = resolve(fields[0], false)
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// Rewrite url() and @import references, resolved against the stylesheet
+func rewriteCSSRefs(css string, base *url.URL, ref refFunc) string {
+	return cssRefPattern.ReplaceAllStringFunc(css, func(match string) string {
+		m := cssRefPattern.FindStringSubmatch(match)
+		value, isImport := m[2], false
+		if value == "" {
+			value, isImport = m[5], true
+		}
+		u, err := base.Parse(strings.TrimSpace(value))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return match
+		}
+		u.Fragment = ""
+		local := ref(u.String(), isImport || strings.HasSuffix(u.Path, ".css"))
+		if m[5] != "" {
+			return "@import " + m[4] + local + m[6]
+		}
+		return "url(" + m[1] + local + m[3] + ")"
+	})
+}
+
+// Every element below n, depth first
+func walkElements(n *html.Node, fn func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			fn(c)
+		}
+		walkElements(c, fn)
+	}
+}
+
+func removeAttr(n *html.Node, key string) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if a.Key != key {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRewriteCSSRefs(t *testing.T) {
+	base, _ := url.Parse("https://example.com/css/site.css")
+	ref := func(abs string, css bool) string {
+		if css {
+			return "local/" + abs + "|css"
+		}
+		return "local/" + abs
+	}
+	tests := []struct{ name, css, want string }{
+		{
+			"quoted relative url",
+			`body{background:url("../img/bg.png")}`,
+			`body{background:url("local/https://example.com/img/bg.png")}`,
+		},
+		{
+			"unquoted url with spaces, fragment dropped",
+			`.a{background:url( https://cdn.example.com/x.svg#icon )}`,
+			`.a{background:url(local/https://cdn.example.com/x.svg)}`,
+		},
+		{
+			"import",
+			`@import 'theme.css';`,
+			`@import 'local/https://example.com/css/theme.css|css';`,
+		},
+		{
+			"stylesheet through url()",
+			`@import url(print.css) print;`,
+			`@import url(local/https://example.com/css/print.css|css) print;`,
+		},
+		{
+			"data url left alone",
+			`.b{background:url(data:image/png;base64,AAAA)}`,
+			`.b{background:url(data:image/png;base64,AAAA)}`,
+		},
+		{
+			"no references",
+			`p{color:red}`,
+			`p{color:red}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteCSSRefs(tt.css, base, ref); got != tt.want {
+				t.Errorf("rewriteCSSRefs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Saved next to the downloads of a folder (images/, assets/)
+const assetIndexName = ".asset_index.json"
+
+// ETag and Last-Modified of the downloads in one folder, so a later run
+// into the same folder (-flat, -no-timestamp-folder) can ask the server
+// whether they changed instead of downloading them again
+type assetIndex struct {
+	out *outputDir
+	dir string
+	// What the earlier run saved, by asset URL
+	previous map[string]assetEntry
+
+	mu      sync.Mutex
+	entries map[string]assetEntry
+}
+
+type assetEntry struct {
+	// Relative to the run folder, with forward slashes
+	File         string `json:"file"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// The index an earlier run left in dir, empty when there is none
+func loadAssetIndex(out *outputDir, dir string) *assetIndex {
+	ix := &assetIndex{out: out, dir: dir, entries: make(map[string]assetEntry)}
+	data, err := os.ReadFile(filepath.Join(out.path, dir, assetIndexName))
+	if err == nil {
+		json.Unmarshal(data, &ix.previous)
+	}
+	return ix
+}
+
+// Validators for a conditional request, only when the earlier download
+// went to the same file and it is still there
+func (ix *assetIndex) validators(rawURL, name string) assetEntry {
+	e, ok := ix.previous[rawURL]
+	if !ok || e.File != path.Join(ix.dir, name) {
+		return assetEntry{}
+	}
+	if _, err := os.Stat(filepath.Join(ix.out.path, filepath.FromSlash(e.File))); err != nil {
+		return assetEntry{}
+	}
+	return e
+}
+
+// Remember where rawURL went, when the server sent validators for it
+func (ix *assetIndex) record(rawURL, name string, asset *fetchedAsset) {
+	if asset.ETag == "" && asset.LastModified == "" {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries[rawURL] = assetEntry{File: path.Join(ix.dir, name), ETag: asset.ETag, LastModified: asset.LastModified}
+}
+
+// Replace the earlier index with the downloads of this run
+func (ix *assetIndex) save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if len(ix.entries) == 0 {
+		return nil
+	}
+	_, err := ix.out.writeJSON(path.Join(ix.dir, assetIndexName), ix.entries)
+	return err
+}
@@ -0,0 +1,111 @@
+package scraper
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Chrome flags every browser of a Scraper starts with; the user agent and
+// headless mode are added per page
+func allocatorOptions(o *Options) []chromedp.ExecAllocatorOption {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.WindowSize(o.Width, o.Height),
+		chromedp.Flag("disable-http2", true),
+	)
+	if o.strictTLS() {
+		// Certificate errors are decided per host before navigating
+		if o.AllowInsecureLocalhost {
+			opts = append(opts, chromedp.Flag("allow-insecure-localhost", true))
+		}
+	} else {
+		opts = append(opts, chromedp.Flag("ignore-certificate-errors", true))
+	}
+	if o.Proxy != "" {
+		opts = append(opts, chromedp.ProxyServer(o.Proxy))
+	}
+	// Without it chromedp starts every browser with a fresh temporary profile
+	if o.UserDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(o.UserDataDir))
+	}
+	// Otherwise chromedp looks for google-chrome, chromium and friends
+	if o.ChromePath != "" {
+		opts = append(opts, chromedp.ExecPath(o.ChromePath))
+	}
+	return opts
+}
+
+// One Chrome for a whole crawl, started with the first page. Every page
+// gets a tab in a browser context of its own, so cookies, storage and
+// cache don't leak between pages the way they wouldn't between browsers.
+type sharedBrowser struct {
+	parent context.Context
+	log    *logger
+	opts   []chromedp.ExecAllocatorOption
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newSharedBrowser(parent context.Context, lg *logger, o *Options) *sharedBrowser {
+	opts := allocatorOptions(o)
+	if o.Headful {
+		// A false flag drops --headless from the defaults
+		opts = append(opts, chromedp.Flag("headless", false))
+	}
+	return &sharedBrowser{parent: parent, log: lg, opts: opts}
+}
+
+// Open a tab with its own deadline. Chrome is started on first use, and
+// again when the previous one went away.
+func (b *sharedBrowser) newTab(timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	b.mu.Lock()
+	if b.ctx == nil || b.ctx.Err() != nil {
+		if b.cancel != nil {
+			b.cancel()
+		}
+		allocCtx, cancelAlloc := chromedp.NewExecAllocator(b.parent, b.opts...)
+		// Tracing the protocol with -verbose
+		var ctxOpts []chromedp.ContextOption
+		if b.log.level == LevelDebug {
+			ctxOpts = append(ctxOpts, chromedp.WithDebugf(b.log.debugf))
+		}
+		ctx, cancelCtx := chromedp.NewContext(allocCtx, ctxOpts...)
+		// Running the empty action list launches the browser
+		if err := chromedp.Run(ctx); err != nil {
+			cancelCtx()
+			cancelAlloc()
+			b.ctx, b.cancel = nil, nil
+			b.mu.Unlock()
+			return nil, nil, err
+		}
+		b.ctx = ctx
+		b.cancel = func() {
+			cancelCtx()
+			cancelAlloc()
+		}
+	}
+	browserCtx := b.ctx
+	b.mu.Unlock()
+
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx, chromedp.WithNewBrowserContext())
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, timeout)
+	// Cancelling the tab context closes the tab and its browser context
+	return tabCtx, func() {
+		cancelTimeout()
+		cancelTab()
+	}, nil
+}
+
+// Shut Chrome down, tabs still open are closed with it
+func (b *sharedBrowser) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cancel != nil {
+		b.cancel()
+		b.ctx, b.cancel = nil, nil
+	}
+}
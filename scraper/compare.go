@@ -0,0 +1,166 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Beyond this many line pairs the diff only reports added and removed
+// lines instead of aligning them
+const maxDiffCells = 4 << 20
+
+func contentHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// An earlier run folder to compare with, read before this run writes
+// anything since it may be the same folder
+type previousRun struct {
+	dir string
+	// What is compared: "text" (text.txt) or "html" (page.html)
+	mode, file string
+	hash, text string
+	err        error
+}
+
+// Hash and content of an earlier run folder, its visible text or with
+// mode "html" its page.html. The content is empty when that run didn't
+// save the file, then only the hash can be compared.
+func loadPreviousRun(dir, mode string) previousRun {
+	prev := previousRun{dir: dir, mode: mode, file: "text.txt"}
+	if mode == "html" {
+		prev.file = "page.html"
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, prev.file)); err == nil {
+		prev.text = string(data)
+		prev.hash = contentHash(prev.text)
+	}
+	if m, err := readManifest(dir); err == nil {
+		hash := m.TextHash
+		if mode == "html" {
+			hash = m.HTMLHash
+		}
+		if hash != "" {
+			prev.hash = hash
+		}
+	}
+	if prev.hash == "" {
+		prev.text, prev.err = "", fmt.Errorf("no %s hash or %s in %s", mode, prev.file, dir)
+	}
+	return prev
+}
+
+// manifest.json of a run folder
+func readManifest(dir string) (*RunManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	var m RunManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Line level diff, "- " for lines only in before and "+ " for lines only in
+// after. Unchanged lines are left out.
+func diffLines(before, after string) []string {
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+
+	// Common head and tail don't need aligning
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+	a, b = a[start:endA], b[start:endB]
+
+	if len(a)*len(b) > maxDiffCells {
+		return unorderedDiff(a, b)
+	}
+
+	// Longest common subsequence table, walked from the front
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, "+ "+b[j])
+	}
+	return diff
+}
+
+// Removed then added lines, counted like multisets
+func unorderedDiff(a, b []string) []string {
+	count := make(map[string]int, len(a))
+	for _, line := range a {
+		count[line]++
+	}
+	var added []string
+	for _, line := range b {
+		if count[line] > 0 {
+			count[line]--
+		} else {
+			added = append(added, "+ "+line)
+		}
+	}
+	var diff []string
+	for _, line := range a {
+		if count[line] > 0 {
+			count[line]--
+			diff = append(diff, "- "+line)
+		}
+	}
+	return append(diff, added...)
+}
+
+// "+3 -1 lines"
+func diffStat(diff []string) string {
+	added, removed := 0, 0
+	for _, line := range diff {
+		if strings.HasPrefix(line, "+") {
+			added++
+		} else {
+			removed++
+		}
+	}
+	return fmt.Sprintf("+%d -%d lines", added, removed)
+}
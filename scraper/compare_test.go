@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name, before, after string
+		want                []string
+	}{
+		{"unchanged", "a\nb\nc", "a\nb\nc", nil},
+		{"changed line", "a\nb\nc", "a\nx\nc", []string{"- b", "+ x"}},
+		{"added line", "a\nc", "a\nb\nc", []string{"+ b"}},
+		{"removed line", "a\nb\nc", "a\nc", []string{"- b"}},
+		{"moved line", "a\nb\nc", "b\nc\na", []string{"- a", "+ a"}},
+		{"from empty", "", "a", []string{"- ", "+ a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnorderedDiff(t *testing.T) {
+	got := unorderedDiff([]string{"a", "b", "b"}, []string{"b", "c"})
+	want := []string{"- a", "- b", "+ c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unorderedDiff = %q, want %q", got, want)
+	}
+}
+
+func TestComparePreviousModes(t *testing.T) {
+	prevDir := t.TempDir()
+	for name, data := range map[string]string{
+		"text.txt":  "Title\nSame text",
+		"page.html": "<h1>Title</h1>\n<p class=\"old\">Same text</p>",
+	} {
+		if err := os.WriteFile(filepath.Join(prevDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the markup changed
+	text, html := "Title\nSame text", "<h1>Title</h1>\n<p class=\"new\">Same text</p>"
+	tests := []struct {
+		mode, content string
+		changed       bool
+		diff          string
+	}{
+		{"text", text, false, ""},
+		{"html", html, true, "- <p class=\"old\">Same text</p>\n+ <p class=\"new\">Same text</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			out := &outputDir{path: t.TempDir(), dirPerm: 0755, filePerm: 0644}
+			manifest := &RunManifest{}
+			prev := loadPreviousRun(prevDir, tt.mode)
+			comparePrevious(newLogger(testWriter{t}, LevelInfo), prev, tt.content, contentHash(tt.content), manifest, out)
+			if manifest.DiffMode != tt.mode || manifest.ComparedWith != prevDir {
+				t.Errorf("manifest diff mode %q compared with %q", manifest.DiffMode, manifest.ComparedWith)
+			}
+			if manifest.Changed != tt.changed {
+				t.Errorf("changed = %v, want %v", manifest.Changed, tt.changed)
+			}
+			data, err := os.ReadFile(filepath.Join(out.path, "diff.txt"))
+			if tt.diff == "" {
+				if err == nil {
+					t.Errorf("unexpected diff.txt: %q", data)
+				}
+				return
+			}
+			if string(data) != tt.diff {
+				t.Errorf("diff.txt = %q, want %q (%v)", data, tt.diff, err)
+			}
+		})
+	}
+}
+
+func TestLoadPreviousRunPrefersManifestHash(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `{"text_hash": "texthash", "html_hash": "htmlhash"}`
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if prev := loadPreviousRun(dir, "html"); prev.hash != "htmlhash" || prev.err != nil {
+		t.Errorf("html: hash %q, err %v", prev.hash, prev.err)
+	}
+	if prev := loadPreviousRun(dir, "text"); prev.hash != "texthash" || prev.text != "" {
+		t.Errorf("text: hash %q, text %q", prev.hash, prev.text)
+	}
+	if prev := loadPreviousRun(t.TempDir(), "text"); prev.err == nil {
+		t.Error("an empty folder should fail to load")
+	}
+}
@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Console calls and uncaught exceptions of a tab, one line each
+type consoleLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func watchConsole(ctx context.Context) *consoleLog {
+	cl := &consoleLog{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		var line string
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			args := make([]string, 0, len(ev.Args))
+			for _, arg := range ev.Args {
+				args = append(args, remoteObjectText(arg))
+			}
+			line = fmt.Sprintf("[%s] %s", ev.Type, strings.Join(args, " "))
+		case *runtime.EventExceptionThrown:
+			line = "[exception] " + exceptionText(ev.ExceptionDetails)
+		default:
+			return
+		}
+		cl.mu.Lock()
+		cl.lines = append(cl.lines, line)
+		cl.mu.Unlock()
+	})
+	return cl
+}
+
+func (cl *consoleLog) text() string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if len(cl.lines) == 0 {
+		return ""
+	}
+	return strings.Join(cl.lines, "\n") + "\n"
+}
+
+func (cl *consoleLog) count() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return len(cl.lines)
+}
+
+// Strings come unquoted, anything else as the browser describes it
+func remoteObjectText(obj *runtime.RemoteObject) string {
+	if obj == nil {
+		return ""
+	}
+	if obj.Type == runtime.TypeString {
+		var s string
+		if err := json.Unmarshal([]byte(obj.Value), &s); err == nil {
+			return s
+		}
+	}
+	switch {
+	case len(obj.Value) > 0:
+		return string(obj.Value)
+	case obj.UnserializableValue != "":
+		return string(obj.UnserializableValue)
+	case obj.Description != "":
+		return obj.Description
+	}
+	return string(obj.Type)
+}
+
+// The exception's description carries the message and stack, the text
+// alone is often just "Uncaught"
+func exceptionText(d *runtime.ExceptionDetails) string {
+	if d == nil {
+		return ""
+	}
+	msg := d.Text
+	if d.Exception != nil && d.Exception.Description != "" {
+		msg = d.Exception.Description
+	}
+	if d.URL != "" {
+		msg += fmt.Sprintf(" (%s:%d:%d)", d.URL, d.LineNumber+1, d.ColumnNumber+1)
+	}
+	return msg
+}
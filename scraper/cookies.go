@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// One cookie of the -cookies file. Value is a pointer so a missing value
+// can be told apart from an empty one.
+type cookieEntry struct {
+	Name     string  `json:"name"`
+	Value    *string `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path,omitempty"`
+	Secure   bool    `json:"secure,omitempty"`
+	HTTPOnly bool    `json:"httpOnly,omitempty"`
+	// Unix seconds, session cookie when 0
+	Expires float64 `json:"expires,omitempty"`
+}
+
+// Read a JSON array of cookies. Name, value and domain are required,
+// path defaults to "/".
+func loadCookies(path string) ([]*network.CookieParam, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []cookieEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid cookies in %s: %v", path, err)
+	}
+
+	cookies := make([]*network.CookieParam, 0, len(entries))
+	for i, c := range entries {
+		switch {
+		case c.Name == "":
+			return nil, fmt.Errorf("cookie %d in %s has no name", i+1, path)
+		case c.Value == nil:
+			return nil, fmt.Errorf("cookie %q in %s has no value", c.Name, path)
+		case c.Domain == "":
+			return nil, fmt.Errorf("cookie %q in %s has no domain", c.Name, path)
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    *c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+		}
+		if c.Expires > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(0, int64(c.Expires*float64(time.Second))))
+			param.Expires = &expires
+		}
+		cookies = append(cookies, param)
+	}
+	return cookies, nil
+}
+
+// Install cookies in the browser; must run before the first navigation
+func setCookies(ctx context.Context, cookies []*network.CookieParam) error {
+	err := chromedp.Run(ctx,
+		network.Enable(),
+		network.SetCookies(cookies),
+	)
+	if err != nil {
+		return fmt.Errorf("error setting cookies: %v", err)
+	}
+	return nil
+}
+
+// Cookies each host's pages ended with, handed to the next page of the
+// same host. Every page runs in a browser context of its own, so without
+// this a crawl would lose its session and see the consent banner on every
+// page.
+type cookieJar struct {
+	mu    sync.Mutex
+	hosts map[string][]*network.CookieParam
+}
+
+func newCookieJar() *cookieJar {
+	return &cookieJar{hosts: make(map[string][]*network.CookieParam)}
+}
+
+// Cookies to install before loading a page of host, none on a nil jar
+func (j *cookieJar) get(host string) []*network.CookieParam {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*network.CookieParam{}, j.hosts[strings.ToLower(host)]...)
+}
+
+// Take over every cookie the browser holds after a page of host, third
+// party ones included (consent providers, SSO). Parallel pages of a host
+// each replace the cookies with theirs, the last one to finish wins.
+func (j *cookieJar) save(ctx context.Context, host string) error {
+	var cookies []*network.Cookie
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = storage.GetCookies().Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return fmt.Errorf("error reading cookies: %v", err)
+	}
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		param := &network.CookieParam{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       c.Domain,
+			Path:         c.Path,
+			Secure:       c.Secure,
+			HTTPOnly:     c.HTTPOnly,
+			SameSite:     c.SameSite,
+			Priority:     c.Priority,
+			PartitionKey: c.PartitionKey,
+		}
+		if !c.Session {
+			expires := cdp.TimeSinceEpoch(time.Unix(0, int64(c.Expires*float64(time.Second))))
+			param.Expires = &expires
+		}
+		params = append(params, param)
+	}
+	j.mu.Lock()
+	j.hosts[strings.ToLower(host)] = params
+	j.mu.Unlock()
+	return nil
+}
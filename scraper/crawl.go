@@ -0,0 +1,398 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page waiting in the crawl queue
+type crawlItem struct {
+	url   string
+	depth int
+}
+
+// Scrape the start URLs, then breadth-first every same-host link up to
+// -depth levels. External links are never followed, each URL is scraped
+// once and -max-pages bounds how many pages get queued, start URLs included.
+// Crawls of more than one page keep crawl_state.json up to date, -resume
+// starts from one instead of from scratch. A -depth crawl ends with every
+// link it found in all_links.txt.
+func crawl(ctx context.Context, o *Options, env *runEnv, starts []string) []PageSummary {
+	lg := env.log
+	var queue []crawlItem
+	var summaries []PageSummary
+	capped := false
+	visited := make(map[string]bool)
+	scheduled := 0
+	// Finished pages, for the state file
+	var pages []stateItem
+	inventory := make(linkInventory)
+	if st := env.resume; st != nil {
+		for _, key := range st.Visited {
+			visited[key] = true
+		}
+		scheduled = st.Scheduled
+		for _, p := range st.Pages {
+			if scrapedBefore(p) {
+				pages = append(pages, p)
+				inventory.load(lg, p.Dir)
+				summaries = append(summaries, PageSummary{URL: p.URL, Skipped: "already scraped"})
+				continue
+			}
+			// Failed, interrupted or its files are gone: once more
+			queue = append(queue, crawlItem{url: p.URL, depth: p.Depth})
+		}
+		for _, q := range st.Queue {
+			queue = append(queue, crawlItem{url: q.URL, depth: q.Depth})
+		}
+		lg.infof("Resuming the crawl: %d pages done, %d to go\n", len(pages), len(queue))
+	}
+	for _, start := range starts {
+		// A bad start URL fails on its own, the others still run
+		rawURL, err := normalizeInputURL(start)
+		if err != nil {
+			lg.Printf("Skipping %q: %v\n", start, err)
+			summaries = append(summaries, newPageSummary(start, nil, err))
+			continue
+		}
+		key := crawlKey(rawURL)
+		if visited[key] {
+			continue
+		}
+		// Long URL lists stop at -max-pages like crawls do
+		if o.MaxPages > 0 && scheduled >= o.MaxPages {
+			lg.Printf("Reached -max-pages %d, skipping the remaining start URLs\n", o.MaxPages)
+			capped = true
+			break
+		}
+		visited[key] = true
+		queue = append(queue, crawlItem{url: rawURL})
+		scheduled++
+	}
+
+	statePath := o.Resume
+	// -check-only leaves no files behind, the state file included
+	if o.CheckOnly {
+		statePath = ""
+	} else if statePath == "" && !o.NoFiles && (o.Depth > 0 || len(queue) > 1) {
+		statePath = filepath.Join(o.Out, "crawl_state.json")
+	}
+	running := make(map[string]crawlItem)
+	var lastSave time.Time
+	saveState := func(force bool) {
+		if statePath == "" || (!force && time.Since(lastSave) < crawlStateInterval) {
+			return
+		}
+		lastSave = time.Now()
+		st := &crawlState{Pages: pages, Scheduled: scheduled}
+		for _, item := range running {
+			st.Queue = append(st.Queue, stateItem{URL: item.url, Depth: item.depth})
+		}
+		for _, item := range queue {
+			st.Queue = append(st.Queue, stateItem{URL: item.url, Depth: item.depth})
+		}
+		for key := range visited {
+			st.Visited = append(st.Visited, key)
+		}
+		if err := saveCrawlState(statePath, st, o.dirMode(), o.fileMode()); err != nil {
+			lg.Printf("Failed to save crawl state: %v\n", err)
+		}
+	}
+
+	// Workers scrape, this loop alone owns the queue and the visited set
+	throttle := newHostThrottle(env.log, o.Delay)
+	work := make(chan crawlItem)
+	done := make(chan crawlResult)
+	// Workers open tabs in one browser instead of starting Chrome for
+	// every page. A -user-data-dir profile is locked by the Chrome using
+	// it, pages that need their own browser couldn't start one next to it.
+	var shared *sharedBrowser
+	if o.UserDataDir == "" {
+		shared = newSharedBrowser(ctx, env.log, o)
+		defer shared.close()
+	}
+	limit := newConcurrencyLimit(o)
+	for i := 0; i < limit.max; i++ {
+		go func() {
+			for item := range work {
+				done <- scrapeItem(ctx, o, env, shared, throttle, item)
+			}
+		}()
+	}
+	defer close(work)
+
+	stopped := false
+	for inFlight := 0; len(queue) > 0 || inFlight > 0; {
+		// Cancelled: let running pages wrap up, start nothing new. The
+		// queue stays for the state file.
+		if ctx.Err() != nil && !stopped {
+			stopped = true
+			if len(queue) > 0 {
+				lg.Printf("Interrupted, %d queued pages are not scraped\n", len(queue))
+			}
+		}
+		if stopped && inFlight == 0 {
+			break
+		}
+		// A nil channel never receives, so nothing is sent while the queue is empty
+		var send chan crawlItem
+		var next crawlItem
+		if len(queue) > 0 && !stopped && inFlight < limit.current {
+			send, next = work, queue[0]
+		}
+		select {
+		case send <- next:
+			queue = queue[1:]
+			running[next.url] = next
+			inFlight++
+		case r := <-done:
+			inFlight--
+			delete(running, r.item.url)
+			finished := stateItem{URL: r.item.url, Depth: r.item.depth}
+			if r.result != nil {
+				finished.Dir = r.result.Dir
+				if m := r.result.Manifest; r.err == nil && m != nil && !m.Interrupted {
+					finished.Complete, finished.Files = true, m.Files
+				}
+			}
+			pages = append(pages, finished)
+			limit.record(lg, r)
+			if r.result != nil {
+				if page, err := url.Parse(r.item.url); err == nil {
+					inventory.add(r.result.Links, page.Hostname(), o.IncludeSubdomains)
+				}
+			}
+			summaries = append(summaries, newPageSummary(r.item.url, r.result, r.err))
+			r.follow(o, func(link string) bool {
+				if o.MaxPages > 0 && scheduled >= o.MaxPages {
+					if !capped {
+						lg.Printf("Reached -max-pages %d, not following more links\n", o.MaxPages)
+						capped = true
+					}
+					return false
+				}
+				key := crawlKey(link)
+				if !visited[key] {
+					visited[key] = true
+					queue = append(queue, crawlItem{url: link, depth: r.item.depth + 1})
+					scheduled++
+				}
+				return true
+			})
+			saveState(false)
+		}
+	}
+	if o.Depth > 0 && !o.NoFiles && !o.CheckOnly {
+		path := filepath.Join(o.Out, "all_links.txt")
+		if err := inventory.save(path, o.dirMode(), o.fileMode()); err != nil {
+			lg.Printf("Failed to save the crawl's links: %v\n", err)
+		} else {
+			lg.infof("%d unique links of the crawl saved to %s\n", len(inventory), path)
+		}
+	}
+	if statePath != "" {
+		saveState(true)
+		if len(queue) > 0 {
+			lg.infof("Crawl state saved to %s, -resume %s continues the crawl\n", statePath, statePath)
+		} else {
+			lg.infof("Crawl state saved to %s\n", statePath)
+		}
+	}
+	return summaries
+}
+
+// A finished page, handed back from a worker
+type crawlResult struct {
+	item   crawlItem
+	result *Result
+	err    error
+}
+
+// Hand fn the same-host links of a page that may be followed, until it
+// returns false
+func (r crawlResult) follow(o *Options, fn func(link string) bool) {
+	if r.err != nil || r.result == nil || r.item.depth >= o.Depth {
+		return
+	}
+	page, err := url.Parse(r.item.url)
+	if err != nil {
+		return
+	}
+	internal, _ := splitLinks(r.result.Links, page.Hostname(), o.IncludeSubdomains)
+	for _, link := range internal {
+		if !fn(link) {
+			return
+		}
+	}
+}
+
+// Wait for the host's turn, then scrape in a tab of the shared browser
+func scrapeItem(ctx context.Context, o *Options, env *runEnv, shared *sharedBrowser, throttle *hostThrottle, item crawlItem) crawlResult {
+	if err := throttle.wait(ctx, item.url); err != nil {
+		return crawlResult{item: item, err: err}
+	}
+	result, err := scrapePage(ctx, o, env, shared, item.url)
+	if err != nil {
+		env.log.Printf("Failed to scrape %s: %v\n", item.url, err)
+	}
+	return crawlResult{item: item, result: result, err: err}
+}
+
+// Pages the crawl loads at once. Fixed at -concurrency unless
+// -adaptive-concurrency, then halved (down to min) when a page answers 429
+// or times out, and raised by one again after as many successes in a row
+// as pages are allowed.
+type concurrencyLimit struct {
+	adaptive      bool
+	min, max      int
+	current, wins int
+}
+
+func newConcurrencyLimit(o *Options) *concurrencyLimit {
+	l := &concurrencyLimit{adaptive: o.AdaptiveConcurrency, max: max(1, o.Concurrency)}
+	l.min = min(max(1, o.AdaptiveMinConcurrency), l.max)
+	l.current = l.max
+	return l
+}
+
+func (l *concurrencyLimit) record(lg *logger, r crawlResult) {
+	if !l.adaptive {
+		return
+	}
+	overloaded := errors.Is(r.err, context.DeadlineExceeded) ||
+		(r.result != nil && r.result.StatusCode == http.StatusTooManyRequests)
+	switch {
+	case overloaded:
+		l.wins = 0
+		if next := max(l.min, l.current/2); next < l.current {
+			lg.Printf("%s was throttled or timed out, lowering the concurrency from %d to %d\n", r.item.url, l.current, next)
+			l.current = next
+		}
+	case r.err == nil:
+		l.wins++
+		if l.current < l.max && l.wins >= l.current {
+			l.wins = 0
+			l.current++
+			lg.infof("Pages load fine again, raising the concurrency to %d\n", l.current)
+		}
+	}
+}
+
+// Visited set key, so /page and /page#top count as one page
+func crawlKey(rawURL string) string {
+	links := normalizeLinks(nil, []pageLink{{URL: rawURL}}, false, false)
+	if len(links) == 0 {
+		return rawURL
+	}
+	return links[0].URL
+}
+
+// Minimum gap between navigations to the same host; other hosts don't wait.
+// Parallel workers each reserve the next free slot of the host.
+type hostThrottle struct {
+	log   *logger
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time
+}
+
+func newHostThrottle(lg *logger, delay time.Duration) *hostThrottle {
+	return &hostThrottle{log: lg, delay: delay, next: make(map[string]time.Time)}
+}
+
+// Sleep until rawURL's host may be loaded again
+func (t *hostThrottle) wait(ctx context.Context, rawURL string) error {
+	if t.delay <= 0 {
+		return nil
+	}
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Host)
+	}
+	t.mu.Lock()
+	at := time.Now()
+	if next, ok := t.next[host]; ok && next.After(at) {
+		at = next
+	}
+	t.next[host] = at.Add(t.delay)
+	t.mu.Unlock()
+
+	if d := time.Until(at); d > 0 {
+		t.log.debugf("Waiting %s before the next request to %s\n", d.Round(time.Millisecond), host)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+	return nil
+}
+
+// Every link a crawl found, true once it was internal to the page it was on
+type linkInventory map[string]bool
+
+func (inv linkInventory) add(links []string, host string, includeSubdomains bool) {
+	internal, external := splitLinks(links, host, includeSubdomains)
+	for _, link := range internal {
+		inv[link] = true
+	}
+	for _, link := range external {
+		if _, ok := inv[link]; !ok {
+			inv[link] = false
+		}
+	}
+}
+
+// Links of a page scraped before -resume, from the run folder it left
+func (inv linkInventory) load(lg *logger, dir string) {
+	for name, internal := range map[string]bool{"links_internal.txt": true, "links_external.txt": false} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			lg.debugf("No %s in %s: %v\n", name, dir, err)
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if link := strings.TrimSpace(scanner.Text()); link != "" && (internal || !inv[link]) {
+				inv[link] = internal
+			}
+		}
+		f.Close()
+	}
+}
+
+// "internal<TAB>url" lines, then the external ones, each sorted
+func (inv linkInventory) save(path string, dirPerm, filePerm os.FileMode) error {
+	links := make([]string, 0, len(inv))
+	for link := range inv {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if inv[links[i]] != inv[links[j]] {
+			return inv[links[i]]
+		}
+		return links[i] < links[j]
+	})
+	var b strings.Builder
+	for _, link := range links {
+		kind := "external"
+		if inv[link] {
+			kind = "internal"
+		}
+		fmt.Fprintf(&b, "%s\t%s\n", kind, link)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(b.String()), filePerm)
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Small site for -static-links crawls, /a and /b link to each other
+func crawlFixture(t *testing.T) *httptest.Server {
+	pages := map[string]string{
+		"/":  `<a href="/a">A</a> <a href="https://example.com/out">Out</a> <a href="mailto:someone@example.com">Mail</a>`,
+		"/a": `<a href="/b">B</a> <a href="/">Home</a> <a href="https://example.com/out#top">Out again</a>`,
+		"/b": `<a href="/a">A</a> <a href="https://example.org/">Other</a>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body><p>Page %s</p>%s</body></html>", r.URL.Path, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Crawl options that never need a browser
+func staticCrawlOptions(t *testing.T) Options {
+	return Options{
+		Out:          t.TempDir(),
+		Timeout:      time.Minute,
+		IgnoreRobots: true,
+		StaticLinks:  true,
+		LinksFormat:  "txt",
+		LogOutput:    testWriter{t},
+	}
+}
+
+func TestCrawlWritesAllLinks(t *testing.T) {
+	srv := crawlFixture(t)
+	o := staticCrawlOptions(t)
+	o.Depth = 2
+	s, err := New(o)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	summaries := s.Crawl(context.Background(), []string{srv.URL + "/"})
+	if len(summaries) != 3 {
+		t.Fatalf("crawled %d pages, want 3: %v", len(summaries), summaries)
+	}
+	data, err := os.ReadFile(filepath.Join(o.Out, "all_links.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "internal\t" + srv.URL + "/\n" +
+		"internal\t" + srv.URL + "/a\n" +
+		"internal\t" + srv.URL + "/b\n" +
+		"external\thttps://example.com/out\n" +
+		"external\thttps://example.org/\n"
+	if string(data) != want {
+		t.Errorf("all_links.txt = %q, want %q", data, want)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	lg := newLogger(testWriter{t}, LevelInfo)
+	ok := crawlResult{result: &Result{StatusCode: http.StatusOK}}
+	throttled := crawlResult{result: &Result{StatusCode: http.StatusTooManyRequests}}
+	timedOut := crawlResult{err: fmt.Errorf("%w: %w", ErrNavigation, context.DeadlineExceeded)}
+	failed := crawlResult{err: ErrNavigation}
+
+	l := newConcurrencyLimit(&Options{Concurrency: 8, AdaptiveConcurrency: true, AdaptiveMinConcurrency: 2})
+	steps := []struct {
+		r    crawlResult
+		want int
+	}{
+		{ok, 8},
+		{throttled, 4},
+		{timedOut, 2},
+		{throttled, 2},
+		// Other failures neither lower nor count as a success
+		{failed, 2},
+		{ok, 2},
+		{ok, 3},
+		{ok, 3},
+		{ok, 3},
+		{ok, 4},
+	}
+	for i, step := range steps {
+		l.record(lg, step.r)
+		if l.current != step.want {
+			t.Fatalf("step %d: concurrency %d, want %d", i, l.current, step.want)
+		}
+	}
+
+	fixed := newConcurrencyLimit(&Options{Concurrency: 3})
+	fixed.record(lg, throttled)
+	if fixed.current != 3 {
+		t.Errorf("without -adaptive-concurrency the limit moved to %d", fixed.current)
+	}
+}
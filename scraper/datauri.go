@@ -0,0 +1,89 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// src="..." and srcset="..." attribute values
+	srcAttrPattern = regexp.MustCompile(`(?i)(\b(?:src|srcset)\s*=\s*)("[^"]*"|'[^']*')`)
+	// url(data:...) inside inline styles and <style> blocks
+	cssURLPattern = regexp.MustCompile(`(?i)url\(\s*(["']?)(data:[^"')]*)(["']?)\s*\)`)
+	// A data URI stops at whitespace (srcset descriptors) or a quote
+	dataURIPattern = regexp.MustCompile(`(?i)data:[^,\s"']*,[^\s"']*`)
+)
+
+// A stripped data URI, kept so it can be written out separately
+type dataURI struct {
+	ID    string
+	Value string
+}
+
+// Replace data: URIs in img src/srcset and CSS url() with a short
+// placeholder that still is a valid URI, so nothing gets fetched when
+// the saved page is opened
+func stripDataURIs(html string) (string, []dataURI) {
+	var stripped []dataURI
+	seen := make(map[string]bool)
+	replace := func(uri string) string {
+		sum := sha256.Sum256([]byte(uri))
+		id := hex.EncodeToString(sum[:6])
+		if !seen[id] {
+			seen[id] = true
+			stripped = append(stripped, dataURI{ID: id, Value: uri})
+		}
+		return "data:,stripped-" + id
+	}
+
+	html = srcAttrPattern.ReplaceAllStringFunc(html, func(attr string) string {
+		return dataURIPattern.ReplaceAllStringFunc(attr, replace)
+	})
+	html = cssURLPattern.ReplaceAllStringFunc(html, func(css string) string {
+		m := cssURLPattern.FindStringSubmatch(css)
+		return "url(" + m[1] + replace(m[2]) + m[3] + ")"
+	})
+	return html, stripped
+}
+
+// Decode each stripped URI into dir/<id>.<ext> of the run folder
+func saveDataURIs(out *outputDir, dir string, uris []dataURI) (int, error) {
+	saved := 0
+	for _, uri := range uris {
+		header, payload, _ := strings.Cut(uri.Value[len("data:"):], ",")
+		mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+		if i := strings.Index(mediaType, ";"); i >= 0 {
+			mediaType = mediaType[:i]
+		}
+
+		var data []byte
+		var err error
+		if isBase64 {
+			data, err = base64.StdEncoding.DecodeString(payload)
+		} else {
+			var text string
+			text, err = url.PathUnescape(payload)
+			data = []byte(text)
+		}
+		if err != nil {
+			// Keep going, one malformed URI should not lose the rest
+			continue
+		}
+
+		ext := ".bin"
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+		if _, err := out.writeFile(filepath.Join(dir, uri.ID+ext), data); err != nil {
+			return saved, err
+		}
+		saved++
+	}
+	return saved, nil
+}
@@ -0,0 +1,60 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Screen and browser identity of an emulated device
+type DeviceProfile struct {
+	Width     int64
+	Height    int64
+	Scale     float64
+	UserAgent string
+}
+
+// Built-in -device table, portrait sizes in CSS pixels
+var devices = map[string]DeviceProfile{
+	"iPhoneSE": {320, 568, 2,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1"},
+	"iPhone12": {390, 844, 3,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15E148 Safari/604.1"},
+	"iPhone14ProMax": {430, 932, 3,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1"},
+	"Pixel5": {393, 851, 2.75,
+		"Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"},
+	"GalaxyS20": {360, 800, 3,
+		"Mozilla/5.0 (Linux; Android 10; SM-G981B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"},
+	"iPadAir": {820, 1180, 2,
+		"Mozilla/5.0 (iPad; CPU OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15E148 Safari/604.1"},
+}
+
+// Case-insensitive lookup; unknown names list what is available
+func LookupDevice(name string) (DeviceProfile, error) {
+	var names []string
+	for key, profile := range devices {
+		if strings.EqualFold(key, name) {
+			return profile, nil
+		}
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return DeviceProfile{}, fmt.Errorf("unknown -device %q, available: %s", name, strings.Join(names, ", "))
+}
+
+// Mobile viewport with touch; the user agent is set on the browser itself
+func emulateDevice(ctx context.Context, d DeviceProfile) error {
+	err := chromedp.Run(ctx, chromedp.EmulateViewport(d.Width, d.Height,
+		chromedp.EmulateScale(d.Scale),
+		chromedp.EmulateMobile,
+		chromedp.EmulateTouch,
+	))
+	if err != nil {
+		return fmt.Errorf("error emulating device: %v", err)
+	}
+	return nil
+}
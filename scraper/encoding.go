@@ -0,0 +1,59 @@
+package scraper
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Encodings we can undo; sending our own Accept-Encoding turns off the
+// transport's transparent gzip handling, so decodeBody has to cover all
+const acceptEncoding = "gzip, deflate, br"
+
+// Wrap the response body so it reads as plain bytes. Stacked encodings
+// ("gzip, br") are undone in reverse order.
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	body := io.Reader(resp.Body)
+	encodings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch enc := strings.ToLower(strings.TrimSpace(encodings[i])); enc {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			r, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding gzip body: %v", err)
+			}
+			body = r
+		case "deflate":
+			body = deflateReader(body)
+		case "br":
+			body = brotli.NewReader(body)
+		default:
+			return nil, fmt.Errorf("unsupported Content-Encoding %q", enc)
+		}
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}, nil
+}
+
+// "deflate" should be zlib wrapped, but some servers send raw deflate
+func deflateReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	// A zlib header is CM=8 and a multiple of 31 when read as big endian
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(br)
+}
@@ -0,0 +1,129 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+const encodingFixture = `<html><body>
+<a href="/one">One</a>
+<a href="two">Two</a>
+<a href="https://example.com/three">Three</a>
+</body></html>`
+
+func compress(t *testing.T, encoding, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		var err error
+		if w, err = flate.NewWriter(&buf, flate.DefaultCompression); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		return []byte(body)
+	}
+	if _, err := io.WriteString(w, body); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetchStaticLinksDecodesBody(t *testing.T) {
+	tests := []struct {
+		name, header, encoding string
+	}{
+		{"identity", "", ""},
+		{"gzip", "gzip", "gzip"},
+		{"brotli", "br", "br"},
+		{"zlib deflate", "deflate", "deflate"},
+		{"raw deflate", "deflate", "raw-deflate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := compress(t, tt.encoding, encodingFixture)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != acceptEncoding {
+					t.Errorf("Accept-Encoding = %q, want %q", got, acceptEncoding)
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				if tt.header != "" {
+					w.Header().Set("Content-Encoding", tt.header)
+				}
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			page, err := fetchStaticLinks(srv.Client(), srv.URL+"/dir/page", "test-agent", nil, "", "")
+			if err != nil {
+				t.Fatalf("fetchStaticLinks: %v", err)
+			}
+			want := []string{srv.URL + "/one", srv.URL + "/dir/two", "https://example.com/three"}
+			if got := linkURLs(page.Links); strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("links = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name, header string
+		body         []byte
+		wantErr      bool
+	}{
+		{"none", "", []byte("plain"), false},
+		{"identity", "identity", []byte("plain"), false},
+		{"gzip", "gzip", compress(t, "gzip", "plain"), false},
+		{"x-gzip", "x-gzip", compress(t, "gzip", "plain"), false},
+		{"br", "br", compress(t, "br", "plain"), false},
+		{"raw deflate", "deflate", compress(t, "raw-deflate", "plain"), false},
+		{"stacked", "deflate, gzip", compress(t, "gzip", string(compress(t, "deflate", "plain"))), false},
+		{"unknown", "zstd", []byte("plain"), true},
+		{"broken gzip", "gzip", []byte("not gzip"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": {tt.header}},
+				Body:   io.NopCloser(bytes.NewReader(tt.body)),
+			}
+			body, err := decodeBody(resp)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeBody: %v", err)
+			}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+			if string(data) != "plain" {
+				t.Errorf("body = %q, want %q", data, "plain")
+			}
+		})
+	}
+}
@@ -0,0 +1,525 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+type breadcrumb struct {
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	URL      string `json:"url,omitempty"`
+}
+
+// Source is "json-ld", "markup" or empty when no trail was found
+type breadcrumbTrail struct {
+	Source string       `json:"source,omitempty"`
+	Items  []breadcrumb `json:"items"`
+}
+
+func extractBreadcrumbs(ctx context.Context) (*breadcrumbTrail, error) {
+	// JSON-LD BreadcrumbList wins, the markup is only a fallback
+	javascript := `(() => {
+		const abs = (href) => { try { return href ? new URL(href, document.baseURI).href : ''; } catch (e) { return ''; } };
+		const lists = [];
+		const walk = (node) => {
+			if (Array.isArray(node)) { node.forEach(walk); return; }
+			if (!node || typeof node !== 'object') return;
+			const type = [].concat(node['@type'] || []);
+			if (type.includes('BreadcrumbList')) lists.push(node);
+			if (node['@graph']) walk(node['@graph']);
+		};
+		for (const s of document.querySelectorAll('script[type="application/ld+json"]')) {
+			try { walk(JSON.parse(s.textContent)); } catch (e) {}
+		}
+		for (const list of lists) {
+			const items = [].concat(list.itemListElement || []).map((el, i) => {
+				const item = el.item;
+				const url = typeof item === 'string' ? item : (item && (item['@id'] || item.url)) || el.url || '';
+				const name = el.name || (item && typeof item === 'object' && item.name) || '';
+				return { position: Number(el.position) || i + 1, name: String(name).trim(), url: abs(url) };
+			});
+			if (items.length) {
+				items.sort((a, b) => a.position - b.position);
+				return { source: 'json-ld', items };
+			}
+		}
+
+		const nav = document.querySelector('nav[aria-label="breadcrumb" i], [itemtype*="BreadcrumbList"], .breadcrumb, .breadcrumbs');
+		if (!nav) return { items: [] };
+		let parts = Array.from(nav.querySelectorAll('li'));
+		if (!parts.length) parts = Array.from(nav.querySelectorAll('a, [aria-current]'));
+		const items = parts.map((el) => {
+			const a = el.tagName === 'A' ? el : el.querySelector('a');
+			return { name: el.innerText.trim(), url: a ? abs(a.getAttribute('href')) : '' };
+		}).filter((it) => it.name).map((it, i) => ({ position: i + 1, ...it }));
+		return items.length ? { source: 'markup', items } : { items: [] };
+	})()`
+
+	var trail breadcrumbTrail
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &trail)); err != nil {
+		return nil, fmt.Errorf("error extracting breadcrumbs: %v", err)
+	}
+	return &trail, nil
+}
+
+// Page complexity metrics for the manifest
+type domStats struct {
+	Nodes    int            `json:"nodes"`
+	MaxDepth int            `json:"max_depth"`
+	Tags     map[string]int `json:"tags"`
+}
+
+func extractDOMStats(ctx context.Context) (*domStats, error) {
+	// Iterative walk so very deep pages can't overflow the JS stack
+	javascript := `(() => {
+		let nodes = 0, maxDepth = 0;
+		const stack = [[document.documentElement, 1]];
+		while (stack.length) {
+			const [el, depth] = stack.pop();
+			nodes++;
+			if (depth > maxDepth) maxDepth = depth;
+			for (const child of el.children) stack.push([child, depth + 1]);
+		}
+		const tags = {};
+		for (const tag of ['div', 'script', 'iframe', 'img', 'style', 'svg']) {
+			tags[tag] = document.getElementsByTagName(tag).length;
+		}
+		return { nodes, max_depth: maxDepth, tags };
+	})()`
+
+	var stats domStats
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &stats)); err != nil {
+		return nil, fmt.Errorf("error computing DOM stats: %v", err)
+	}
+	return &stats, nil
+}
+
+// A named CSS selector from name=selector flags
+type FieldSelector struct {
+	Name     string
+	Selector string
+}
+
+func ParseFieldSelectors(values []string) ([]FieldSelector, error) {
+	var fields []FieldSelector
+	for _, value := range values {
+		name, selector, ok := strings.Cut(value, "=")
+		name, selector = strings.TrimSpace(name), strings.TrimSpace(selector)
+		if !ok || name == "" || selector == "" {
+			return nil, fmt.Errorf("invalid field %q, expected name=selector", value)
+		}
+		fields = append(fields, FieldSelector{Name: name, Selector: selector})
+	}
+	return fields, nil
+}
+
+// Text of every element matching each selector, in document order
+func extractAllFields(ctx context.Context, fields []FieldSelector) (map[string][]string, error) {
+	result := make(map[string][]string, len(fields))
+	for _, field := range fields {
+		selectorJSON, _ := json.Marshal(field.Selector)
+		javascript := fmt.Sprintf(`Array.from(document.querySelectorAll(%s)).map(el => (el.innerText || el.textContent || '').trim())`, selectorJSON)
+		var texts []string
+		if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &texts)); err != nil {
+			return nil, fmt.Errorf("error extracting field %q: %v", field.Name, err)
+		}
+		if texts == nil {
+			texts = []string{}
+		}
+		result[field.Name] = texts
+	}
+	return result, nil
+}
+
+// Where a listing page sits in its pagination. Zero values mean unknown.
+type paginationState struct {
+	Detected bool   `json:"detected"`
+	Current  int    `json:"current,omitempty"`
+	Total    int    `json:"total,omitempty"`
+	Next     string `json:"next,omitempty"`
+	Prev     string `json:"prev,omitempty"`
+}
+
+func extractPagination(ctx context.Context) (*paginationState, error) {
+	javascript := `(() => {
+		const abs = (href) => { try { return href ? new URL(href, document.baseURI).href : ''; } catch (e) { return ''; } };
+		const rel = (name) => {
+			const el = document.querySelector('link[rel~="' + name + '"], a[rel~="' + name + '"]');
+			return el ? abs(el.getAttribute('href')) : '';
+		};
+		const state = { next: rel('next'), prev: rel('prev') || rel('previous'), current: 0, total: 0 };
+
+		const box = document.querySelector('nav[aria-label*="pagination" i], .pagination, .pager, [class*="pagination"]');
+		if (box) {
+			const numbers = Array.from(box.querySelectorAll('a, span, li, button'))
+				.map((el) => el.innerText.trim()).filter((t) => /^\d+$/.test(t)).map(Number);
+			if (numbers.length) state.total = Math.max(...numbers);
+			const cur = box.querySelector('[aria-current="page"], .active, .current, .is-active');
+			if (cur && /^\d+$/.test(cur.innerText.trim())) state.current = Number(cur.innerText.trim());
+			if (!state.next) {
+				const a = box.querySelector('a[aria-label*="next" i], a.next, .next a');
+				if (a) state.next = abs(a.getAttribute('href'));
+			}
+			if (!state.prev) {
+				const a = box.querySelector('a[aria-label*="prev" i], a.prev, .prev a, a.previous');
+				if (a) state.prev = abs(a.getAttribute('href'));
+			}
+		}
+
+		// "Page 2 of 10" style indicators
+		const m = (document.body ? document.body.innerText : '').match(/page\s+(\d+)\s+(?:of|\/)\s+(\d+)/i);
+		if (m) {
+			if (!state.current) state.current = Number(m[1]);
+			if (Number(m[2]) > state.total) state.total = Number(m[2]);
+		}
+		if (state.current > state.total) state.total = state.current;
+
+		state.detected = !!(state.next || state.prev || state.current || state.total);
+		return state;
+	})()`
+
+	var state paginationState
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &state)); err != nil {
+		return nil, fmt.Errorf("error extracting pagination: %v", err)
+	}
+	return &state, nil
+}
+
+// One block of the structured text export. Lists carry their entries in
+// Items, which may hold nested lists of their own.
+type textBlock struct {
+	Type    string      `json:"type"`
+	Level   int         `json:"level,omitempty"`
+	Ordered bool        `json:"ordered,omitempty"`
+	Text    string      `json:"text,omitempty"`
+	Items   []textBlock `json:"items,omitempty"`
+}
+
+func extractStructuredText(ctx context.Context) ([]textBlock, error) {
+	// Walk the body in document order, skipping hidden and non-content nodes
+	javascript := `(() => {
+		const skip = /^(SCRIPT|STYLE|NOSCRIPT|TEMPLATE|SVG|IFRAME|HEAD)$/;
+		const hidden = (el) => { const s = getComputedStyle(el); return s.display === 'none' || s.visibility === 'hidden'; };
+		const clean = (t) => (t || '').replace(/\s+/g, ' ').trim();
+
+		const listBlock = (list) => {
+			const items = [];
+			for (const li of list.children) {
+				if (li.tagName !== 'LI' || hidden(li)) continue;
+				const item = { type: 'item', text: '' };
+				const own = [];
+				const nested = [];
+				for (const child of li.childNodes) {
+					if (child.nodeType === 1 && /^(UL|OL)$/.test(child.tagName)) nested.push(listBlock(child));
+					else own.push(child.nodeType === 1 ? child.innerText : child.textContent);
+				}
+				item.text = clean(own.join(' '));
+				if (nested.length) item.items = nested;
+				if (item.text || nested.length) items.push(item);
+			}
+			return { type: 'list', ordered: list.tagName === 'OL', items };
+		};
+
+		const blocks = [];
+		const walk = (el) => {
+			for (const child of el.children) {
+				if (skip.test(child.tagName.toUpperCase()) || hidden(child)) continue;
+				const tag = child.tagName;
+				let m;
+				if ((m = tag.match(/^H([1-6])$/))) {
+					const text = clean(child.innerText);
+					if (text) blocks.push({ type: 'heading', level: Number(m[1]), text });
+				} else if (tag === 'P') {
+					const text = clean(child.innerText);
+					if (text) blocks.push({ type: 'paragraph', text });
+				} else if (tag === 'UL' || tag === 'OL') {
+					const list = listBlock(child);
+					if (list.items.length) blocks.push(list);
+				} else if (tag === 'BLOCKQUOTE') {
+					const text = clean(child.innerText);
+					if (text) blocks.push({ type: 'quote', text });
+				} else if (tag === 'PRE') {
+					const text = child.innerText.trim();
+					if (text) blocks.push({ type: 'code', text });
+				} else if (child.children.length) {
+					walk(child);
+				} else {
+					// Leaf containers such as <div>text</div>
+					const text = clean(child.innerText);
+					if (text && !/^(A|SPAN|B|I|EM|STRONG|LABEL|BUTTON)$/.test(tag)) blocks.push({ type: 'paragraph', text });
+				}
+			}
+		};
+		if (document.body) walk(document.body);
+		return blocks;
+	})()`
+
+	var blocks []textBlock
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &blocks)); err != nil {
+		return nil, fmt.Errorf("error extracting structured text: %v", err)
+	}
+	return blocks, nil
+}
+
+// Canonical URL and hreflang alternates (language -> absolute URL)
+type hreflangInfo struct {
+	Canonical       string            `json:"canonical,omitempty"`
+	Alternates      map[string]string `json:"alternates"`
+	MissingXDefault bool              `json:"missing_x_default"`
+}
+
+func extractHreflang(ctx context.Context) (*hreflangInfo, error) {
+	// link.href is already resolved against the document base
+	javascript := `(() => {
+		const canonical = document.querySelector('link[rel~="canonical"]');
+		const alternates = {};
+		for (const link of document.querySelectorAll('link[rel~="alternate"][hreflang]')) {
+			const lang = link.getAttribute('hreflang').trim().toLowerCase();
+			if (lang && link.href) alternates[lang] = link.href;
+		}
+		return { canonical: canonical ? canonical.href : '', alternates };
+	})()`
+
+	var info hreflangInfo
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &info)); err != nil {
+		return nil, fmt.Errorf("error extracting hreflang: %v", err)
+	}
+	if info.Alternates == nil {
+		info.Alternates = map[string]string{}
+	}
+	_, hasDefault := info.Alternates["x-default"]
+	info.MissingXDefault = len(info.Alternates) > 0 && !hasDefault
+	return &info, nil
+}
+
+// Layout size of the rendered page and the viewport it was rendered in
+type pageDimensions struct {
+	ScrollWidth      int     `json:"scroll_width"`
+	ScrollHeight     int     `json:"scroll_height"`
+	ViewportWidth    int     `json:"viewport_width"`
+	ViewportHeight   int     `json:"viewport_height"`
+	DevicePixelRatio float64 `json:"device_pixel_ratio"`
+}
+
+func extractDimensions(ctx context.Context) (*pageDimensions, error) {
+	javascript := `(() => {
+		const root = document.documentElement, body = document.body || root;
+		return {
+			scroll_width: Math.max(root.scrollWidth, body.scrollWidth),
+			scroll_height: Math.max(root.scrollHeight, body.scrollHeight),
+			viewport_width: window.innerWidth,
+			viewport_height: window.innerHeight,
+			device_pixel_ratio: window.devicePixelRatio,
+		};
+	})()`
+	var dims pageDimensions
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &dims)); err != nil {
+		return nil, fmt.Errorf("error reading page dimensions: %v", err)
+	}
+	return &dims, nil
+}
+
+// Caps for dom.json so huge pages don't produce huge files
+const (
+	DOMJSONMaxDepth = 64
+	DOMJSONMaxNodes = 50000
+)
+
+// Element or text node of dom.json; text nodes only carry Text
+type domNode struct {
+	Tag      string            `json:"tag,omitempty"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Children []*domNode        `json:"children,omitempty"`
+}
+
+// Truncated is set when a cap cut off part of the document
+type domTree struct {
+	Truncated bool     `json:"truncated"`
+	Nodes     int      `json:"nodes"`
+	Root      *domNode `json:"root"`
+}
+
+func extractDOMTree(ctx context.Context) (*domTree, error) {
+	// Whitespace-only text and comments are dropped
+	javascript := fmt.Sprintf(`(() => {
+		const maxDepth = %d, maxNodes = %d;
+		let nodes = 0, truncated = false;
+		const walk = (el, depth) => {
+			nodes++;
+			const node = { tag: el.tagName.toLowerCase() };
+			if (el.attributes.length) {
+				node.attrs = {};
+				for (const a of el.attributes) node.attrs[a.name] = a.value;
+			}
+			if (depth >= maxDepth) {
+				if (el.childNodes.length) truncated = true;
+				return node;
+			}
+			const children = [];
+			for (const child of el.childNodes) {
+				if (nodes >= maxNodes) { truncated = true; break; }
+				if (child.nodeType === Node.ELEMENT_NODE) {
+					children.push(walk(child, depth + 1));
+				} else if (child.nodeType === Node.TEXT_NODE) {
+					const text = child.textContent.trim();
+					if (text) { nodes++; children.push({ text }); }
+				}
+			}
+			if (children.length) node.children = children;
+			return node;
+		};
+		const root = walk(document.documentElement, 0);
+		return { truncated, nodes, root };
+	})()`, DOMJSONMaxDepth, DOMJSONMaxNodes)
+
+	var tree domTree
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &tree)); err != nil {
+		return nil, fmt.Errorf("error serializing DOM: %v", err)
+	}
+	return &tree, nil
+}
+
+// Title, description, canonical and every og:* / twitter:* meta tag.
+// Missing or empty fields are left out.
+func extractMetadata(ctx context.Context) (map[string]string, error) {
+	javascript := `(() => {
+		const meta = {};
+		const set = (key, value) => {
+			value = (value || '').trim();
+			if (value && !(key in meta)) meta[key] = value;
+		};
+		set('title', document.title);
+		const description = document.querySelector('meta[name="description" i]');
+		if (description) set('description', description.getAttribute('content'));
+		const canonical = document.querySelector('link[rel~="canonical" i]');
+		if (canonical) set('canonical', canonical.href);
+		for (const el of document.querySelectorAll('meta[property], meta[name]')) {
+			const key = (el.getAttribute('property') || el.getAttribute('name') || '').trim().toLowerCase();
+			if (key.startsWith('og:') || key.startsWith('twitter:')) set(key, el.getAttribute('content'));
+		}
+		return meta;
+	})()`
+
+	var meta map[string]string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &meta)); err != nil {
+		return nil, fmt.Errorf("error extracting metadata: %v", err)
+	}
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	return meta, nil
+}
+
+// One JSON-LD block or top level microdata item of structured_data.json
+type structuredItem struct {
+	Source string          `json:"source"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// JSON-LD blocks as written in the page, plus microdata items as
+// {type, properties}. Blocks that aren't valid JSON are skipped and
+// returned as errors so the caller can log them.
+func extractStructuredData(ctx context.Context) ([]structuredItem, []error, error) {
+	javascript := `(() => {
+		const jsonld = Array.from(document.querySelectorAll('script[type="application/ld+json"]'), s => s.textContent);
+		const value = (el) => {
+			if (el.hasAttribute('itemscope')) return item(el);
+			switch (el.tagName) {
+			case 'META': return el.getAttribute('content') || '';
+			case 'A': case 'LINK': case 'AREA': return el.href;
+			case 'IMG': case 'AUDIO': case 'VIDEO': case 'SOURCE': case 'IFRAME': case 'EMBED': return el.src;
+			case 'OBJECT': return el.data;
+			case 'TIME': return el.getAttribute('datetime') || el.textContent.trim();
+			case 'DATA': case 'METER': return el.getAttribute('value') || '';
+			}
+			return el.textContent.trim();
+		};
+		const item = (scope) => {
+			const properties = {};
+			for (const el of scope.querySelectorAll('[itemprop]')) {
+				// Properties of nested items belong to those items
+				if (el.parentElement.closest('[itemscope]') !== scope) continue;
+				for (const name of el.getAttribute('itemprop').split(/\s+/).filter(Boolean)) {
+					(properties[name] = properties[name] || []).push(value(el));
+				}
+			}
+			const it = { type: (scope.getAttribute('itemtype') || '').split(/\s+/).filter(Boolean), properties };
+			if (scope.hasAttribute('itemid')) it.id = scope.getAttribute('itemid');
+			return it;
+		};
+		const microdata = Array.from(document.querySelectorAll('[itemscope]:not([itemprop])'), item);
+		return { jsonld, microdata };
+	})()`
+
+	var raw struct {
+		JSONLD    []string          `json:"jsonld"`
+		Microdata []json.RawMessage `json:"microdata"`
+	}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &raw)); err != nil {
+		return nil, nil, fmt.Errorf("error extracting structured data: %v", err)
+	}
+
+	items := []structuredItem{}
+	var skipped []error
+	for i, block := range raw.JSONLD {
+		block = strings.TrimSpace(block)
+		// Some CMSs wrap the JSON in an HTML comment
+		block = strings.TrimSuffix(strings.TrimPrefix(block, "<!--"), "-->")
+		block = strings.TrimSpace(block)
+		if !json.Valid([]byte(block)) {
+			skipped = append(skipped, fmt.Errorf("JSON-LD block %d is not valid JSON", i+1))
+			continue
+		}
+		items = append(items, structuredItem{Source: "json-ld", Data: json.RawMessage(block)})
+	}
+	for _, m := range raw.Microdata {
+		items = append(items, structuredItem{Source: "microdata", Data: m})
+	}
+	return items, skipped, nil
+}
+
+// One input, select or textarea of a form. Values are never recorded, a
+// hidden field is usually a CSRF token.
+type formField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+type pageForm struct {
+	ID      string      `json:"id,omitempty"`
+	Name    string      `json:"name,omitempty"`
+	Action  string      `json:"action"`
+	Method  string      `json:"method"`
+	Enctype string      `json:"enctype,omitempty"`
+	Fields  []formField `json:"fields"`
+}
+
+func extractForms(ctx context.Context) ([]pageForm, error) {
+	// A missing action submits to the page itself; form.elements also
+	// picks up fields outside the form that point at it with form="id"
+	javascript := `(() => {
+		const abs = (href) => { try { return new URL(href || '', document.baseURI).href; } catch (e) { return href || ''; } };
+		return Array.from(document.forms).map(form => ({
+			id: form.id || '',
+			name: form.getAttribute('name') || '',
+			action: abs(form.getAttribute('action')),
+			method: (form.getAttribute('method') || 'get').toUpperCase(),
+			enctype: form.getAttribute('enctype') || '',
+			fields: Array.from(form.elements)
+				.filter(el => ['INPUT', 'SELECT', 'TEXTAREA'].includes(el.tagName))
+				.map(el => ({name: el.name || '', type: (el.type || el.tagName).toLowerCase(), required: !!el.required})),
+		}));
+	})()`
+	var forms []pageForm
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &forms)); err != nil {
+		return nil, fmt.Errorf("error extracting forms: %v", err)
+	}
+	return forms, nil
+}
@@ -0,0 +1,31 @@
+package scraper
+
+// FileDescriptorsPerPage is what one page in flight is counted with: the
+// DevTools socket, plain HTTP connections and output files, plus what
+// Chrome opens for a tab under the same limit
+const FileDescriptorsPerPage = 64
+
+// Kept free for everything else: logs, the SQLite store, Chrome itself
+const fdReserve = 64
+
+// Concurrency that fits in limit descriptors, and the limit it went by.
+// A limit of 0 reads RLIMIT_NOFILE, a negative one skips the check.
+func fdSafeConcurrency(requested, limit int) (int, uint64) {
+	if limit < 0 {
+		return requested, 0
+	}
+	fds := uint64(limit)
+	if limit == 0 {
+		var ok bool
+		if fds, ok = openFileLimit(); !ok {
+			return requested, 0
+		}
+	}
+	if fds <= fdReserve+FileDescriptorsPerPage {
+		return 1, fds
+	}
+	if safe := (fds - fdReserve) / FileDescriptorsPerPage; uint64(requested) > safe {
+		return int(safe), fds
+	}
+	return requested, fds
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package scraper
+
+// No descriptor limit to read outside of unix systems
+func openFileLimit() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,25 @@
+package scraper
+
+import "testing"
+
+func TestFDSafeConcurrency(t *testing.T) {
+	tests := []struct {
+		name             string
+		requested, limit int
+		want             int
+	}{
+		{"fits", 4, 1024, 4},
+		{"clamped", 64, 1024, (1024 - fdReserve) / FileDescriptorsPerPage},
+		{"tiny limit still scrapes", 8, 16, 1},
+		{"check skipped", 500, -1, 500},
+	}
+	for _, tt := range tests {
+		if got, _ := fdSafeConcurrency(tt.requested, tt.limit); got != tt.want {
+			t.Errorf("%s: fdSafeConcurrency(%d, %d) = %d, want %d", tt.name, tt.requested, tt.limit, got, tt.want)
+		}
+	}
+	// The process limit, whatever it is here, always leaves one page
+	if got, _ := fdSafeConcurrency(1<<20, 0); got < 1 {
+		t.Errorf("with the process limit: %d pages", got)
+	}
+}
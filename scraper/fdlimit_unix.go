@@ -0,0 +1,14 @@
+//go:build unix
+
+package scraper
+
+import "syscall"
+
+// Soft RLIMIT_NOFILE of the process, Chrome inherits it
+func openFileLimit() (uint64, bool) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0, false
+	}
+	return uint64(rl.Cur), true
+}
@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// Find the iframe given to -frame, by CSS selector or by URL prefix.
+// Out-of-process (cross-origin) frames have no content document here, so
+// they fail with an explicit error instead of silently extracting nothing.
+func findFrame(ctx context.Context, spec string) (*cdp.Node, error) {
+	var frame *cdp.Node
+	if u, err := url.Parse(spec); err == nil && isHTTPURL(u) {
+		var iframes []*cdp.Node
+		err := chromedp.Run(ctx, chromedp.Nodes("iframe, frame", &iframes, chromedp.ByQueryAll, chromedp.AtLeast(0)))
+		if err != nil {
+			return nil, fmt.Errorf("error listing frames: %v", err)
+		}
+		for _, n := range iframes {
+			if strings.HasPrefix(frameURL(n), spec) {
+				frame = n
+				break
+			}
+		}
+	} else {
+		found, err := elementExists(ctx, spec)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up frame %q: %v", spec, err)
+		}
+		if found {
+			var nodes []*cdp.Node
+			if err := chromedp.Run(ctx, chromedp.Nodes(spec, &nodes, chromedp.ByQuery)); err != nil {
+				return nil, fmt.Errorf("error looking up frame %q: %v", spec, err)
+			}
+			frame = nodes[0]
+		}
+	}
+
+	if frame == nil {
+		return nil, fmt.Errorf("no frame matches %q", spec)
+	}
+	if frame.NodeName != "IFRAME" && frame.NodeName != "FRAME" {
+		return nil, fmt.Errorf("%q matches a <%s>, not a frame", spec, strings.ToLower(frame.NodeName))
+	}
+	if frame.ContentDocument == nil {
+		return nil, fmt.Errorf("frame %q (%s) is cross-origin or not loaded, its document can't be accessed", spec, frameURL(frame))
+	}
+	return frame, nil
+}
+
+// Document URL when loaded, the src attribute otherwise
+func frameURL(n *cdp.Node) string {
+	if n.ContentDocument != nil && n.ContentDocument.DocumentURL != "" {
+		return n.ContentDocument.DocumentURL
+	}
+	return n.AttributeValue("src")
+}
+
+// Rendered HTML of the frame's document
+func frameContent(ctx context.Context, frame *cdp.Node) (string, error) {
+	var htmlContent string
+	err := chromedp.Run(ctx, chromedp.OuterHTML("html", &htmlContent, chromedp.ByQuery, chromedp.FromNode(frame)))
+	if err != nil {
+		return "", fmt.Errorf("error retrieving frame content: %v", err)
+	}
+	return htmlContent, nil
+}
+
+// Links inside the frame, resolved against the frame's own base URL
+func frameLinks(ctx context.Context, frame *cdp.Node) ([]pageLink, error) {
+	base, err := url.Parse(frame.ContentDocument.BaseURL)
+	if err != nil || frame.ContentDocument.BaseURL == "" {
+		base, _ = url.Parse(frameURL(frame))
+	}
+
+	var anchors []*cdp.Node
+	err = chromedp.Run(ctx, chromedp.Nodes("a[href]", &anchors, chromedp.ByQueryAll, chromedp.AtLeast(0), chromedp.FromNode(frame)))
+	if err != nil {
+		return nil, fmt.Errorf("error extracting frame links: %v", err)
+	}
+	links := []pageLink{}
+	for _, a := range anchors {
+		ref, err := url.Parse(strings.TrimSpace(a.AttributeValue("href")))
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		// Best effort, a link without its text is still a link
+		var text string
+		_ = chromedp.Run(ctx, chromedp.Text([]cdp.NodeID{a.NodeID}, &text, chromedp.ByNodeID, chromedp.NodeReady))
+		links = append(links, pageLink{URL: ref.String(), Text: text})
+	}
+	return links, nil
+}
@@ -0,0 +1,187 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Heuristic verdict on whether the content sits behind a login or paywall
+type gateReport struct {
+	Gated   bool     `json:"gated"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+const detectGatesJS = `(() => {
+	const reasons = [];
+	const visible = (el) => el.getClientRects().length > 0 && getComputedStyle(el).visibility !== 'hidden';
+
+	if (Array.from(document.querySelectorAll('input[type="password"]')).some(visible)) {
+		reasons.push('password field');
+	}
+
+	const text = (document.body ? document.body.innerText : '').toLowerCase();
+	const phrases = [
+		'subscribe to continue', 'subscribe to read', 'subscribers only', 'for subscribers',
+		'sign in to continue', 'log in to continue', 'login to continue', 'sign in to read',
+		'create a free account', 'register to continue', 'already a subscriber',
+		'you have reached your limit', 'free articles remaining', 'to continue reading',
+	];
+	for (const p of phrases) {
+		if (text.includes(p)) { reasons.push('text: "' + p + '"'); break; }
+	}
+
+	const tier = document.querySelector('meta[property="article:content_tier"]');
+	if (tier && /locked|metered/i.test(tier.content)) reasons.push('meta content_tier=' + tier.content);
+	for (const s of document.querySelectorAll('script[type="application/ld+json"]')) {
+		if (/"isAccessibleForFree"\s*:\s*("false"|false)/i.test(s.textContent)) { reasons.push('json-ld isAccessibleForFree=false'); break; }
+	}
+
+	const wall = document.querySelector('[class*="paywall" i], [id*="paywall" i], [class*="regwall" i], .tp-modal, .piano-offer');
+	if (wall && visible(wall)) reasons.push('paywall element');
+
+	// Article cut short under a fade or "read more" overlay
+	const article = document.querySelector('article, main');
+	if (article && article.innerText.trim().length < 600 && article.querySelector('[class*="fade" i], [class*="truncat" i]')) {
+		reasons.push('truncated content');
+	}
+	return { gated: reasons.length > 0, reasons };
+})()`
+
+func detectGates(ctx context.Context) (*gateReport, error) {
+	var report gateReport
+	if err := chromedp.Run(ctx, chromedp.Evaluate(detectGatesJS, &report)); err != nil {
+		return nil, fmt.Errorf("error detecting gates: %v", err)
+	}
+	return &report, nil
+}
+
+// Anti-bot challenge found on the page instead of the real content
+type captchaReport struct {
+	Detected bool     `json:"detected"`
+	Provider string   `json:"provider,omitempty"`
+	Signals  []string `json:"signals,omitempty"`
+}
+
+const detectCaptchaJS = `(() => {
+	const signals = [];
+	let provider = '';
+	const hit = (name, signal) => { if (!provider) provider = name; signals.push(signal); };
+
+	const frames = Array.from(document.querySelectorAll('iframe')).map((f) => f.src || '');
+	const scripts = Array.from(document.querySelectorAll('script[src]')).map((s) => s.src);
+	const sources = frames.concat(scripts);
+	const has = (re) => sources.some((src) => re.test(src));
+
+	if (has(/google\.com\/recaptcha|recaptcha\.net/) || document.querySelector('.g-recaptcha')) hit('recaptcha', 'reCAPTCHA widget');
+	if (has(/hcaptcha\.com/) || document.querySelector('.h-captcha')) hit('hcaptcha', 'hCaptcha widget');
+	if (has(/challenges\.cloudflare\.com/) || document.querySelector('#challenge-form, #cf-challenge-running, .cf-turnstile, #challenge-stage')) {
+		hit('cloudflare', 'Cloudflare challenge');
+	}
+	if (/^(just a moment|attention required)/i.test(document.title)) hit('cloudflare', 'title: ' + document.title);
+	if (has(/captcha-delivery\.com/)) hit('datadome', 'DataDome challenge');
+	if (document.querySelector('#px-captcha')) hit('perimeterx', 'PerimeterX challenge');
+
+	const text = (document.body ? document.body.innerText : '').toLowerCase();
+	for (const p of ['verify you are human', 'checking your browser', 'are you a robot', "confirm you're not a robot", 'press & hold']) {
+		if (text.includes(p)) { hit('unknown', 'text: "' + p + '"'); break; }
+	}
+	return { detected: signals.length > 0, provider, signals };
+})()`
+
+func detectCaptcha(ctx context.Context) (*captchaReport, error) {
+	var report captchaReport
+	if err := chromedp.Run(ctx, chromedp.Evaluate(detectCaptchaJS, &report)); err != nil {
+		return nil, fmt.Errorf("error detecting captcha: %v", err)
+	}
+	return &report, nil
+}
+
+// Cookie consent overlay covering the page, and whether it was dismissed
+type consentReport struct {
+	Detected bool   `json:"detected"`
+	Provider string `json:"provider,omitempty"`
+	// Label of the button -auto-accept-cookies clicked
+	Clicked  string `json:"clicked,omitempty"`
+	Accepted bool   `json:"accepted,omitempty"`
+}
+
+// Consent platforms by the container they render, generic patterns last.
+// Banners inside iframes (Sourcepoint and similar) aren't reachable from
+// the page and are only seen through their frame's URL.
+const consentBannersJS = `
+	const visible = (el) => el.getClientRects().length > 0 && getComputedStyle(el).visibility !== 'hidden' && getComputedStyle(el).display !== 'none';
+	const banners = [
+		['onetrust', '#onetrust-banner-sdk, #onetrust-consent-sdk .ot-sdk-container'],
+		['cookiebot', '#CybotCookiebotDialog'],
+		['didomi', '#didomi-notice, #didomi-popup'],
+		['quantcast', '.qc-cmp2-container'],
+		['trustarc', '#truste-consent-track, .truste_box_overlay'],
+		['usercentrics', '#usercentrics-root, #uc-banner'],
+		['cookieyes', '.cky-consent-container'],
+		['osano', '.osano-cm-window'],
+		['sourcepoint', 'iframe[id^="sp_message_iframe"], iframe[src*="privacy-mgmt.com"]'],
+		['generic', '[id*="cookie-banner" i], [class*="cookie-banner" i], [id*="cookie-consent" i], [class*="cookie-consent" i], [id*="cookieconsent" i], [class*="cookieconsent" i], [aria-label*="cookie" i][role="dialog"]'],
+	];
+	const findBanner = () => {
+		for (const [provider, selector] of banners) {
+			const el = Array.from(document.querySelectorAll(selector)).find(visible);
+			if (el) return { provider, el };
+		}
+		return null;
+	};`
+
+const detectConsentJS = `(() => {` + consentBannersJS + `
+	const found = findBanner();
+	return { detected: !!found, provider: found ? found.provider : '' };
+})()`
+
+// Known accept buttons first, then any button of the banner whose label
+// reads like "Accept all"; never "reject" or "settings" ones
+const acceptConsentJS = `(() => {` + consentBannersJS + `
+	const known = '#onetrust-accept-btn-handler, #CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll, #CybotCookiebotDialogBodyButtonAccept, ' +
+		'#didomi-notice-agree-button, .qc-cmp2-summary-buttons button[mode="primary"], #truste-consent-button, ' +
+		'[data-testid="uc-accept-all-button"], .cky-btn-accept, .osano-cm-accept-all';
+	const label = (el) => (el.innerText || el.value || el.getAttribute('aria-label') || '').trim();
+	let button = Array.from(document.querySelectorAll(known)).find(visible);
+	if (!button) {
+		const found = findBanner();
+		const scope = found && found.el.tagName !== 'IFRAME' ? found.el : document;
+		const accept = /^(accept|accept all|accept cookies|allow all|allow cookies|agree|i agree|agree and close|got it|ok|okay)\b/i;
+		button = Array.from(scope.querySelectorAll('button, a[role="button"], input[type="button"], input[type="submit"]'))
+			.find((el) => visible(el) && accept.test(label(el)) && !/reject|decline|settings|manage|customi[sz]e/i.test(label(el)));
+	}
+	if (!button) return '';
+	button.click();
+	return label(button) || button.id || 'button';
+})()`
+
+func detectConsent(ctx context.Context) (*consentReport, error) {
+	var report consentReport
+	if err := chromedp.Run(ctx, chromedp.Evaluate(detectConsentJS, &report)); err != nil {
+		return nil, fmt.Errorf("error detecting consent banner: %v", err)
+	}
+	return &report, nil
+}
+
+// Click the banner's accept button and check the banner is gone once the
+// page had settle time to react
+func acceptConsent(ctx context.Context, report *consentReport, settle time.Duration) error {
+	if err := chromedp.Run(ctx, chromedp.Evaluate(acceptConsentJS, &report.Clicked)); err != nil {
+		return fmt.Errorf("error accepting cookies: %v", err)
+	}
+	if report.Clicked == "" {
+		return nil
+	}
+	if err := chromedp.Run(ctx, chromedp.Sleep(settle)); err != nil {
+		return err
+	}
+	after, err := detectConsent(ctx)
+	if err != nil {
+		return err
+	}
+	report.Accepted = !after.Detected
+	return nil
+}
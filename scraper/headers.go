@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// ParseHeaders turns "Name: Value" flags into a header set. A name given
+// twice keeps both values, joined the way HTTP combines repeated headers.
+func ParseHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid -header %q, expected \"Name: Value\"", v)
+		}
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid -header %q, %q is not a valid header name", v, name)
+		}
+		if !validHeaderValue(value) {
+			return nil, fmt.Errorf("invalid -header %q, the value has control characters", v)
+		}
+		name = http.CanonicalHeaderKey(name)
+		if old, ok := headers[name]; ok {
+			value = old + ", " + value
+		}
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+// RFC 9110 token characters
+func validHeaderName(name string) bool {
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// No control characters except tab, so a value can't smuggle in a new line
+func validHeaderValue(value string) bool {
+	for _, r := range value {
+		if (r < ' ' && r != '\t') || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// Sent with every request of the tab, the document and its subresources
+func setExtraHeaders(ctx context.Context, headers map[string]string) error {
+	h := make(network.Headers, len(headers))
+	for name, value := range headers {
+		h[name] = value
+	}
+	if err := chromedp.Run(ctx, network.SetExtraHTTPHeaders(h)); err != nil {
+		return fmt.Errorf("failed to set extra headers: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Elements whose content must be kept byte for byte
+var preservedElements = map[string]bool{
+	"pre": true, "textarea": true, "script": true, "style": true, "title": true,
+}
+
+// Elements that never have an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+// Re-serialize the page as "pretty", "minify" or leave it as is for "raw"
+func formatHTML(src, mode string) (string, error) {
+	if mode == "" || mode == "raw" {
+		return src, nil
+	}
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return "", fmt.Errorf("error parsing HTML: %v", err)
+	}
+
+	var buf bytes.Buffer
+	switch mode {
+	case "minify":
+		minifyNode(doc)
+		err = html.Render(&buf, doc)
+	case "pretty":
+		err = prettyRender(&buf, doc, 0)
+	default:
+		return "", fmt.Errorf("unknown HTML format %q", mode)
+	}
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Drop comments and collapse whitespace outside of preserved elements
+func minifyNode(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode:
+			n.RemoveChild(c)
+		case html.TextNode:
+			c.Data = whitespaceRun.ReplaceAllString(c.Data, " ")
+			if strings.TrimSpace(c.Data) == "" && (c.PrevSibling == nil || c.NextSibling == nil ||
+				c.PrevSibling.Type != html.ElementNode || c.NextSibling.Type != html.ElementNode) {
+				n.RemoveChild(c)
+			}
+		case html.ElementNode:
+			if !preservedElements[c.Data] {
+				minifyNode(c)
+			}
+		default:
+			minifyNode(c)
+		}
+		c = next
+	}
+}
+
+// Indent every element on its own line, two spaces per level
+func prettyRender(buf *bytes.Buffer, n *html.Node, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	switch n.Type {
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if err := prettyRender(buf, c, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	case html.TextNode:
+		if text := strings.TrimSpace(whitespaceRun.ReplaceAllString(n.Data, " ")); text != "" {
+			buf.WriteString(indent + html.EscapeString(text) + "\n")
+		}
+		return nil
+	case html.ElementNode:
+		if preservedElements[n.Data] {
+			// Let the standard renderer keep the raw content intact
+			buf.WriteString(indent)
+			if err := html.Render(buf, n); err != nil {
+				return err
+			}
+			buf.WriteString("\n")
+			return nil
+		}
+		buf.WriteString(indent + "<" + n.Data)
+		for _, a := range n.Attr {
+			key := a.Key
+			if a.Namespace != "" {
+				key = a.Namespace + ":" + key
+			}
+			buf.WriteString(" " + key + `="` + html.EscapeString(a.Val) + `"`)
+		}
+		buf.WriteString(">\n")
+		if voidElements[n.Data] {
+			return nil
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if err := prettyRender(buf, c, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(indent + "</" + n.Data + ">\n")
+		return nil
+	default:
+		// Doctype and comments
+		buf.WriteString(indent)
+		if err := html.Render(buf, n); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		return nil
+	}
+}
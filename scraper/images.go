@@ -0,0 +1,198 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Default -download-concurrency, kept small to stay polite
+const imageWorkers = 4
+
+// Largest single image that is saved
+const maxImageBytes = 50 << 20
+
+// Every <img> src and srcset candidate, resolved and without data: URIs
+func extractImageURLs(ctx context.Context) ([]string, error) {
+	javascript := `(() => {
+		const urls = new Set();
+		const add = (value) => {
+			if (!value) return;
+			try {
+				const u = new URL(value.trim(), document.baseURI);
+				if (u.protocol === 'http:' || u.protocol === 'https:') urls.add(u.href);
+			} catch (e) {}
+		};
+		for (const img of document.querySelectorAll('img, picture source')) {
+			add(img.getAttribute('src'));
+			for (const candidate of (img.getAttribute('srcset') || '').split(',')) {
+				add(candidate.trim().split(/\s+/)[0]);
+			}
+		}
+		return Array.from(urls);
+	})()`
+	var urls []string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &urls)); err != nil {
+		return nil, fmt.Errorf("error collecting images: %v", err)
+	}
+	return urls, nil
+}
+
+// Download every image into dir, as many at once as slots allow. Failures
+// are logged per image; the number of saved files is returned.
+func downloadImages(ctx context.Context, lg *logger, client *http.Client, slots semaphore, out *outputDir, dir string, urls []string, userAgent string) int {
+	var mu sync.Mutex
+	saved := 0
+	names := imageFileNames(urls)
+	// Stop reading as soon as an image can't be saved anyway
+	limit := int64(maxImageBytes)
+	if out.maxFileSize > 0 && out.maxFileSize < limit {
+		limit = out.maxFileSize
+	}
+
+	index := loadAssetIndex(out, dir)
+	err := fetchEach(ctx, slots, len(urls), func(i int) {
+		name := filepath.Join(dir, names[i])
+		asset, err := fetchAsset(client, urls[i], userAgent, limit, index.validators(urls[i], names[i]))
+		switch {
+		case err != nil:
+		case asset.NotModified:
+			lg.debugf("Image %s is unchanged, keeping %s\n", urls[i], name)
+			out.keep(name)
+		default:
+			_, err = out.writeCapped(name, asset.Data)
+		}
+		if err != nil {
+			lg.Printf("Failed to download image %s: %v\n", urls[i], err)
+			return
+		}
+		index.record(urls[i], names[i], asset)
+		mu.Lock()
+		saved++
+		mu.Unlock()
+	})
+	if err != nil {
+		lg.Printf("Stopped downloading images: %v\n", err)
+	}
+	if err := index.save(); err != nil {
+		lg.Printf("Failed to save the image index: %v\n", err)
+	}
+	return saved
+}
+
+// Call fetch for 0..n-1, each call holding one of slots while it runs.
+// Stops starting new ones when ctx is done and returns its error.
+func fetchEach(ctx context.Context, slots semaphore, n int, fetch func(i int)) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := range n {
+		if err := slots.acquire(ctx); err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer slots.release()
+			fetch(i)
+		}()
+	}
+	return nil
+}
+
+// A downloaded asset. NotModified means the server confirmed the copy of
+// the earlier run, and Data is empty.
+type fetchedAsset struct {
+	Data         []byte
+	ETag         string
+	LastModified string
+	NotModified  bool
+}
+
+// Download one image or other page asset, at most limit bytes. The
+// validators of an earlier download make it a conditional request.
+func fetchAsset(client *http.Client, rawURL, userAgent string, limit int64, prev assetEntry) (*fetchedAsset, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	asset := &fetchedAsset{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && prev != (assetEntry{}):
+		// A 304 may leave the validators out, they still hold
+		asset.NotModified = true
+		if asset.ETag == "" && asset.LastModified == "" {
+			asset.ETag, asset.LastModified = prev.ETag, prev.LastModified
+		}
+		return asset, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	asset.Data, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(asset.Data)) > limit {
+		return nil, fmt.Errorf("larger than %d bytes", limit)
+	}
+	return asset, nil
+}
+
+// File names from the URL paths, made safe and unique within the folder
+func imageFileNames(urls []string) []string {
+	names := make([]string, len(urls))
+	used := make(map[string]bool, len(urls))
+	for i, rawURL := range urls {
+		name := "image"
+		if u, err := url.Parse(rawURL); err == nil {
+			if base := path.Base(u.Path); base != "/" && base != "." {
+				name = base
+			}
+		}
+		name = sanitizeFileName(name)
+		if used[name] {
+			ext := path.Ext(name)
+			name = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i+1, ext)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// Keep letters, digits, dot, dash and underscore
+func sanitizeFileName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name = strings.TrimLeft(b.String(), ".")
+	if name == "" {
+		return "image"
+	}
+	return name
+}
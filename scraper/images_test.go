@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"photo.jpg", "photo.jpg"},
+		{"my photo (1).png", "my_photo__1_.png"},
+		{"..hidden.gif", "hidden.gif"},
+		{"ünï.gif", "_n_.gif"},
+		{"a/b\\c", "a_b_c"},
+		{"", "image"},
+		{"...", "image"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFileName(tt.name); got != tt.want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImageFileNames(t *testing.T) {
+	urls := []string{
+		"https://example.com/a/cat.jpg",
+		"https://example.com/b/cat.jpg?size=large",
+		"https://example.com/",
+		"https://example.com/img%20one.png",
+		"https://example.com",
+	}
+	want := []string{"cat.jpg", "cat_2.jpg", "image", "img_one.png", "image_5"}
+	if got := imageFileNames(urls); !reflect.DeepEqual(got, want) {
+		t.Errorf("imageFileNames = %q, want %q", got, want)
+	}
+}
+
+func TestDownloadImagesStaysWithinSlots(t *testing.T) {
+	var running, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	var urls []string
+	for i := range 8 {
+		urls = append(urls, fmt.Sprintf("%s/img/%d.png", srv.URL, i))
+	}
+	out := &outputDir{path: t.TempDir(), dirPerm: 0755, filePerm: 0644}
+	lg := newLogger(testWriter{t}, LevelInfo)
+	saved := downloadImages(context.Background(), lg, srv.Client(), newSemaphore(2), out, "images", urls, "test-agent")
+	if saved != len(urls) {
+		t.Errorf("saved %d of %d images", saved, len(urls))
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d downloads ran at once, want at most 2", p)
+	}
+	data, err := os.ReadFile(filepath.Join(out.path, "images", "3.png"))
+	if err != nil || string(data) != "/img/3.png" {
+		t.Errorf("images/3.png = %q, %v", data, err)
+	}
+}
+
+func TestDownloadImagesRevalidates(t *testing.T) {
+	var full atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag.png":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/dated.png":
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			if r.Header.Get("If-Modified-Since") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		full.Add(1)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	urls := []string{srv.URL + "/etag.png", srv.URL + "/dated.png", srv.URL + "/plain.png"}
+	dir := t.TempDir()
+	lg := newLogger(testWriter{t}, LevelInfo)
+	run := func() *outputDir {
+		out := &outputDir{path: dir, dirPerm: 0755, filePerm: 0644}
+		if saved := downloadImages(context.Background(), lg, srv.Client(), newSemaphore(2), out, "images", urls, "test-agent"); saved != len(urls) {
+			t.Fatalf("saved %d of %d images", saved, len(urls))
+		}
+		return out
+	}
+	run()
+	if n := full.Load(); n != 3 {
+		t.Fatalf("first run downloaded %d images, want 3", n)
+	}
+	second := run()
+	// Only the image without validators comes down again
+	if n := full.Load(); n != 4 {
+		t.Errorf("second run downloaded %d images, want 1", n-3)
+	}
+	want := []string{"images/.asset_index.json", "images/dated.png", "images/etag.png", "images/plain.png"}
+	if got := second.written(); !reflect.DeepEqual(got, want) {
+		t.Errorf("written = %v, want %v", got, want)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "images", "etag.png"))
+	if err != nil || string(data) != "/etag.png" {
+		t.Errorf("images/etag.png = %q, %v", data, err)
+	}
+}
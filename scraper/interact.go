@@ -0,0 +1,186 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// Run a user supplied script in the page, awaiting it when it returns a
+// promise, then give the page settle time before capture
+func runEvalHook(ctx context.Context, script string, settle time.Duration) error {
+	err := chromedp.Run(ctx,
+		chromedp.Evaluate(script, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
+		chromedp.Sleep(settle),
+	)
+	if err != nil {
+		return fmt.Errorf("eval-after script failed: %v", err)
+	}
+	return nil
+}
+
+// Pause after each interaction so the page can react
+const interactionSettle = time.Second
+
+// One -key flag: keys typed into the first element matching selector
+type KeyInput struct {
+	Selector string
+	Keys     string
+}
+
+// Names usable as {Name} inside -key values
+var specialKeys = map[string]string{
+	"Enter":      kb.Enter,
+	"Tab":        kb.Tab,
+	"Escape":     kb.Escape,
+	"Backspace":  kb.Backspace,
+	"Delete":     kb.Delete,
+	"ArrowUp":    kb.ArrowUp,
+	"ArrowDown":  kb.ArrowDown,
+	"ArrowLeft":  kb.ArrowLeft,
+	"ArrowRight": kb.ArrowRight,
+}
+
+// Parse selector:keys values. The split is on the last colon so selectors
+// with pseudo classes (input:not([disabled])) still work.
+func ParseKeyInputs(values []string) ([]KeyInput, error) {
+	var inputs []KeyInput
+	for _, value := range values {
+		i := strings.LastIndex(value, ":")
+		if i <= 0 || i == len(value)-1 {
+			return nil, fmt.Errorf("invalid key input %q, expected selector:keys", value)
+		}
+		keys, err := expandKeys(value[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, KeyInput{Selector: strings.TrimSpace(value[:i]), Keys: keys})
+	}
+	return inputs, nil
+}
+
+// Replace {Enter}, {Tab}, ... with the characters chromedp sends as those keys
+func expandKeys(keys string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(keys, "{")
+		if start < 0 {
+			b.WriteString(keys)
+			return b.String(), nil
+		}
+		end := strings.Index(keys[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated key name in %q", keys)
+		}
+		name := keys[start+1 : start+end]
+		key, ok := specialKeys[name]
+		if !ok {
+			return "", fmt.Errorf("unknown key {%s}", name)
+		}
+		b.WriteString(keys[:start])
+		b.WriteString(key)
+		keys = keys[start+end+1:]
+	}
+}
+
+// Reports whether anything matches selector right now, so interactions
+// don't block until the timeout on a missing element
+func elementExists(ctx context.Context, selector string) (bool, error) {
+	selectorJSON, _ := json.Marshal(selector)
+	var found bool
+	err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(`document.querySelector(%s) !== null`, selectorJSON), &found))
+	return found, err
+}
+
+// Focus each element and type its keys, in flag order
+func sendKeyInputs(ctx context.Context, inputs []KeyInput) error {
+	for _, input := range inputs {
+		found, err := elementExists(ctx, input.Selector)
+		if err != nil {
+			return fmt.Errorf("key input on %q failed: %v", input.Selector, err)
+		}
+		if !found {
+			return fmt.Errorf("key input selector %q not found", input.Selector)
+		}
+		err = chromedp.Run(ctx,
+			chromedp.SendKeys(input.Selector, input.Keys, chromedp.ByQuery),
+			chromedp.Sleep(interactionSettle),
+		)
+		if err != nil {
+			return fmt.Errorf("key input on %q failed: %v", input.Selector, err)
+		}
+	}
+	return nil
+}
+
+// Scrolls the element into view and returns its center, null when missing
+const hoverTargetJS = `(() => {
+	const el = document.querySelector(%s);
+	if (!el) return null;
+	el.scrollIntoView({block: 'center'});
+	const r = el.getBoundingClientRect();
+	return {x: r.left + r.width / 2, y: r.top + r.height / 2};
+})()`
+
+// Move the mouse over each element so hover-only content (menus,
+// tooltips) is rendered. Missing selectors are logged and skipped.
+func hoverElements(ctx context.Context, lg *logger, selectors []string) {
+	for _, selector := range selectors {
+		selectorJSON, _ := json.Marshal(selector)
+		var point *struct{ X, Y float64 }
+		if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(hoverTargetJS, selectorJSON), &point)); err != nil {
+			lg.Printf("Failed to hover %q: %v\n", selector, err)
+			continue
+		}
+		if point == nil {
+			lg.Printf("Hover selector %q not found\n", selector)
+			continue
+		}
+		err := chromedp.Run(ctx,
+			chromedp.MouseEvent(input.MouseMoved, point.X, point.Y),
+			chromedp.Sleep(interactionSettle),
+		)
+		if err != nil {
+			lg.Printf("Failed to hover %q: %v\n", selector, err)
+		}
+	}
+}
+
+// Scroll to the bottom until the page stops growing or maxScrolls is
+// reached, for feeds that load more content on scroll. Returns how many
+// scrolls made the page taller. The page is scrolled back to the top so
+// the screenshot starts where a visitor would.
+func autoScroll(ctx context.Context, lg *logger, maxScrolls int, pause time.Duration) (int, error) {
+	var height int64
+	if err := chromedp.Run(ctx, chromedp.Evaluate(`document.body.scrollHeight`, &height)); err != nil {
+		return 0, err
+	}
+	grew := 0
+	for i := 0; i < maxScrolls; i++ {
+		var next int64
+		err := chromedp.Run(ctx,
+			chromedp.Evaluate(`window.scrollTo(0, document.body.scrollHeight)`, nil),
+			chromedp.Sleep(pause),
+			chromedp.Evaluate(`document.body.scrollHeight`, &next),
+		)
+		if err != nil {
+			return grew, err
+		}
+		if next <= height {
+			break
+		}
+		lg.debugf("Auto scroll %d: page height %d -> %d", i+1, height, next)
+		height = next
+		grew++
+	}
+	return grew, chromedp.Run(ctx, chromedp.Evaluate(`window.scrollTo(0, 0)`, nil))
+}
@@ -0,0 +1,41 @@
+package scraper
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Result is everything from one scrape, printed by -json and passed to
+// -template
+type Result struct {
+	URL        string `json:"url"`
+	StatusCode int64  `json:"status_code"`
+	// Saved page.html, left out of the JSON document
+	HTML     string            `json:"-"`
+	Title    string            `json:"title,omitempty"`
+	Links    []string          `json:"links"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Visible page text, in the JSON document only with JSONText
+	Text     string       `json:"text,omitempty"`
+	Manifest *RunManifest `json:"manifest"`
+	// Base64 encoded in the JSON document, only with -json-screenshot
+	Screenshot []byte `json:"screenshot,omitempty"`
+	// Run folder the files went to, empty when nothing was written
+	Dir string `json:"-"`
+}
+
+func writeJSONResult(w io.Writer, r *Result, withScreenshot, withText bool) error {
+	doc := *r
+	if doc.Links == nil {
+		doc.Links = []string{}
+	}
+	if !withScreenshot {
+		doc.Screenshot = nil
+	}
+	if !withText {
+		doc.Text = ""
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&doc)
+}
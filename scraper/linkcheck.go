@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Parallel link checks and how long each may take
+const (
+	linkCheckWorkers = 8
+	linkCheckTimeout = 15 * time.Second
+)
+
+// Answer for one link; Status is 0 when the server couldn't be reached
+type linkStatus struct {
+	URL    string
+	Status int
+	Err    error
+}
+
+func (l linkStatus) broken() bool {
+	return l.Status >= 400 || l.Err != nil
+}
+
+// Status of every http(s) link, in the order given. Each host gets the
+// usual -delay between requests.
+func checkLinks(ctx context.Context, lg *logger, client *http.Client, links []string, userAgent string, delay time.Duration) []linkStatus {
+	var checked []linkStatus
+	for _, link := range links {
+		if u, err := url.Parse(link); err == nil && isHTTPURL(u) {
+			checked = append(checked, linkStatus{URL: link})
+		}
+	}
+	// Same transport as the other plain HTTP requests, so -proxy applies
+	client = &http.Client{Timeout: linkCheckTimeout, Transport: client.Transport}
+	throttle := newHostThrottle(lg, delay)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < linkCheckWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := throttle.wait(ctx, checked[i].URL); err != nil {
+					checked[i].Err = err
+					continue
+				}
+				checked[i].Status, checked[i].Err = linkStatusCode(ctx, client, checked[i].URL, userAgent)
+			}
+		}()
+	}
+	for i := range checked {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return checked
+}
+
+// HEAD first; servers that refuse or mishandle it get a GET
+func linkStatusCode(ctx context.Context, client *http.Client, link, userAgent string) (int, error) {
+	status, err := requestStatus(ctx, client, http.MethodHead, link, userAgent)
+	if err == nil && status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented && status != http.StatusForbidden {
+		return status, nil
+	}
+	return requestStatus(ctx, client, http.MethodGet, link, userAgent)
+}
+
+func requestStatus(ctx context.Context, client *http.Client, method, link, userAgent string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// A little of the body is drained so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// link_status.csv, url,status with status 0 for unreachable links
+func linkStatusCSV(checked []linkStatus) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"url", "status"})
+	for _, l := range checked {
+		w.Write([]string{l.URL, strconv.Itoa(l.Status)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Anchor found on the page, Text is its visible text
+type pageLink struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
+}
+
+// Just the URLs, in order
+func linkURLs(links []pageLink) []string {
+	urls := make([]string, len(links))
+	for i, l := range links {
+		urls[i] = l.URL
+	}
+	return urls
+}
+
+// Resolve links against base, drop fragments unless keepFragments, and
+// return them deduplicated and sorted so links.txt diffs cleanly between
+// runs. Duplicates keep the first non-empty text. Links that don't parse
+// are kept as they are.
+func normalizeLinks(base *url.URL, links []pageLink, keepFragments, stripTrailingSlash bool) []pageLink {
+	index := make(map[string]int, len(links))
+	normalized := []pageLink{}
+	for _, l := range links {
+		link := strings.TrimSpace(l.URL)
+		if link == "" {
+			continue
+		}
+		if u, err := url.Parse(link); err == nil {
+			if base != nil {
+				u = base.ResolveReference(u)
+			}
+			u.Scheme = strings.ToLower(u.Scheme)
+			u.Host = strings.ToLower(u.Host)
+			if !keepFragments {
+				u.Fragment = ""
+				u.RawFragment = ""
+			}
+			if stripTrailingSlash && len(u.Path) > 1 {
+				u.Path = strings.TrimRight(u.Path, "/")
+				u.RawPath = ""
+			}
+			link = u.String()
+		}
+		text := strings.Join(strings.Fields(l.Text), " ")
+		if i, ok := index[link]; ok {
+			if normalized[i].Text == "" {
+				normalized[i].Text = text
+			}
+			continue
+		}
+		index[link] = len(normalized)
+		normalized = append(normalized, pageLink{URL: link, Text: text})
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].URL < normalized[j].URL })
+	return normalized
+}
+
+// Schemes that run script or hand off to another app instead of loading a
+// page
+var pseudoSchemes = map[string]bool{"javascript": true, "mailto": true, "tel": true}
+
+// Split off javascript:, mailto: and tel: links, nothing can fetch or
+// crawl them
+func splitPseudoLinks(links []pageLink) (pages, pseudo []pageLink) {
+	pages, pseudo = []pageLink{}, []pageLink{}
+	for _, l := range links {
+		if u, err := url.Parse(l.URL); err == nil && pseudoSchemes[strings.ToLower(u.Scheme)] {
+			pseudo = append(pseudo, l)
+		} else {
+			pages = append(pages, l)
+		}
+	}
+	return pages, pseudo
+}
+
+// Split links into those on the page's host and the rest. Subdomains count
+// as internal only with includeSubdomains; links without a host (mailto:,
+// javascript:) are in neither list.
+func splitLinks(links []string, host string, includeSubdomains bool) (internal, external []string) {
+	internal, external = []string{}, []string{}
+	for _, link := range links {
+		isInternal, ok := internalLink(link, host, includeSubdomains)
+		switch {
+		case !ok:
+		case isInternal:
+			internal = append(internal, link)
+		default:
+			external = append(external, link)
+		}
+	}
+	return internal, external
+}
+
+// Whether link points at host; ok is false for links without a host
+func internalLink(link, host string, includeSubdomains bool) (internal, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil || u.Hostname() == "" {
+		return false, false
+	}
+	h, host := strings.ToLower(u.Hostname()), strings.ToLower(host)
+	return h == host || (includeSubdomains && strings.HasSuffix(h, "."+host)), true
+}
+
+// links.<format> contents: one URL per line for txt, url/text objects for
+// json, url,text,internal rows for csv
+func formatLinks(links []pageLink, format, host string, includeSubdomains bool) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(links, "", "  ")
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"url", "text", "internal"})
+		for _, l := range links {
+			internal, _ := internalLink(l.URL, host, includeSubdomains)
+			w.Write([]string{l.URL, l.Text, strconv.FormatBool(internal)})
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	default:
+		return []byte(strings.Join(linkURLs(links), "\n")), nil
+	}
+}
@@ -0,0 +1,141 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// Every kind of href a page throws at us, served at /dir/sub/page
+const hrefsFixture = `<html><body>
+<a href="rel">Relative</a>
+<a href="../up">Up</a>
+<a href="?q=1">Query</a>
+<a href="//cdn.example.com/x">Protocol relative</a>
+<a href="https://example.com/abs">Absolute</a>
+<a href="javascript:void(0)">Script</a>
+<a href="mailto:someone@example.com">Mail</a>
+<a href="TEL:+15550100">Phone</a>
+</body></html>`
+
+// What hrefsFixture resolves to, pages then pseudo links
+func hrefsFixtureWant(srvURL string) (pages, pseudo []string) {
+	return []string{
+		srvURL + "/dir/sub/rel",
+		srvURL + "/dir/up",
+		srvURL + "/dir/sub/page?q=1",
+		"http://cdn.example.com/x",
+		"https://example.com/abs",
+	}, []string{
+		"javascript:void(0)",
+		"mailto:someone@example.com",
+		"tel:+15550100",
+	}
+}
+
+func TestNormalizeLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/dir/page")
+	tests := []struct {
+		name       string
+		links      []pageLink
+		keepFrag   bool
+		stripSlash bool
+		want       []pageLink
+	}{
+		{
+			name:  "relative and absolute",
+			links: []pageLink{{URL: "other"}, {URL: "/top"}, {URL: "https://b.example.org/x"}},
+			want: []pageLink{
+				{URL: "https://b.example.org/x"},
+				{URL: "https://example.com/dir/other"},
+				{URL: "https://example.com/top"},
+			},
+		},
+		{
+			name:  "scheme and host lowercased, fragment dropped",
+			links: []pageLink{{URL: "HTTPS://Example.COM/Path#section"}},
+			want:  []pageLink{{URL: "https://example.com/Path"}},
+		},
+		{
+			name:     "fragment kept",
+			links:    []pageLink{{URL: "/a#section"}},
+			keepFrag: true,
+			want:     []pageLink{{URL: "https://example.com/a#section"}},
+		},
+		{
+			name:       "trailing slash stripped but not from the root",
+			links:      []pageLink{{URL: "/a/"}, {URL: "/"}},
+			stripSlash: true,
+			want:       []pageLink{{URL: "https://example.com/"}, {URL: "https://example.com/a"}},
+		},
+		{
+			name: "duplicates keep the first non-empty text",
+			links: []pageLink{
+				{URL: "/a"},
+				{URL: "https://example.com/a#top", Text: "  First \n link "},
+				{URL: "/a", Text: "Second"},
+			},
+			want: []pageLink{{URL: "https://example.com/a", Text: "First link"}},
+		},
+		{
+			name:  "blank links dropped, malformed kept",
+			links: []pageLink{{URL: "  "}, {URL: "http://[::1"}},
+			want:  []pageLink{{URL: "http://[::1"}},
+		},
+		{
+			name:  "none",
+			links: nil,
+			want:  []pageLink{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeLinks(base, tt.links, tt.keepFrag, tt.stripSlash)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeLinks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitPseudoLinks(t *testing.T) {
+	links := []pageLink{
+		{URL: "https://example.com/a"},
+		{URL: "javascript:void(0)"},
+		{URL: "mailto:someone@example.com"},
+		{URL: "tel:+15550100"},
+		{URL: "ftp://example.com/file"},
+	}
+	pages, pseudo := splitPseudoLinks(links)
+	wantPages := []string{"https://example.com/a", "ftp://example.com/file"}
+	wantPseudo := []string{"javascript:void(0)", "mailto:someone@example.com", "tel:+15550100"}
+	if got := linkURLs(pages); !reflect.DeepEqual(got, wantPages) {
+		t.Errorf("pages = %v, want %v", got, wantPages)
+	}
+	if got := linkURLs(pseudo); !reflect.DeepEqual(got, wantPseudo) {
+		t.Errorf("pseudo = %v, want %v", got, wantPseudo)
+	}
+}
+
+func TestFetchStaticLinksResolvesHrefs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(hrefsFixture))
+	}))
+	defer srv.Close()
+
+	page, err := fetchStaticLinks(srv.Client(), srv.URL+"/dir/sub/page", "test-agent", nil, "", "")
+	if err != nil {
+		t.Fatalf("fetchStaticLinks: %v", err)
+	}
+	pages, pseudo := splitPseudoLinks(page.Links)
+	wantPages, wantPseudo := hrefsFixtureWant(srv.URL)
+	if got := linkURLs(pages); !reflect.DeepEqual(got, wantPages) {
+		t.Errorf("pages = %v, want %v", got, wantPages)
+	}
+	if got := linkURLs(pseudo); !reflect.DeepEqual(got, wantPseudo) {
+		t.Errorf("pseudo = %v, want %v", got, wantPseudo)
+	}
+}
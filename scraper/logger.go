@@ -0,0 +1,61 @@
+package scraper
+
+import (
+	"io"
+	"log"
+)
+
+// LogLevel is how much is printed: errors always, progress unless -quiet,
+// chromedp protocol traffic and step details only with -verbose. The zero
+// value is LevelInfo.
+type LogLevel int
+
+const (
+	LevelInfo LogLevel = iota
+	LevelError
+	LevelDebug
+)
+
+// Output of one Scraper: errors, progress and -verbose details all go
+// through its own *log.Logger, so lines never interleave and the program
+// embedding the package keeps the standard logger as it is
+type logger struct {
+	*log.Logger
+	level LogLevel
+}
+
+func newLogger(w io.Writer, level LogLevel) *logger {
+	return &logger{Logger: log.New(w, "", log.LstdFlags), level: level}
+}
+
+// Copy for one page, every line carries the run ID
+func (lg *logger) withRunID(runID string) *logger {
+	return &logger{Logger: log.New(lg.Writer(), lg.Prefix()+"["+runID+"] ", lg.Flags()), level: lg.level}
+}
+
+// Progress, skipped with LevelError
+func (lg *logger) infof(format string, args ...any) {
+	if lg.level == LevelError {
+		return
+	}
+	lg.Printf(format, args...)
+}
+
+// Step details, only with LevelDebug
+func (lg *logger) debugf(format string, args ...any) {
+	if lg.level != LevelDebug {
+		return
+	}
+	lg.Printf("debug: "+format, args...)
+}
+
+// Infof prints progress through the Scraper's logger, unless its level is
+// LevelError
+func (s *Scraper) Infof(format string, args ...any) {
+	s.env.log.infof(format, args...)
+}
+
+// Printf prints an error through the Scraper's logger, whatever the level
+func (s *Scraper) Printf(format string, args ...any) {
+	s.env.log.Printf(format, args...)
+}
@@ -0,0 +1,141 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+)
+
+// Short ID derived from the URL and start time, so the same run always
+// maps to the same ID in logs, manifest and folder names
+func newRunID(rawURL string, start time.Time) string {
+	sum := sha256.Sum256([]byte(rawURL + "|" + start.Format(time.RFC3339Nano)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// RunManifest is the machine readable record of one scrape run
+type RunManifest struct {
+	RunID      string `json:"run_id"`
+	URL        string `json:"url"`
+	Timestamp  string `json:"timestamp"`
+	StatusCode int64  `json:"status_code"`
+	// Requested URL to landing page when the page redirected
+	Redirects []redirectHop `json:"redirects,omitempty"`
+	FinalURL  string        `json:"final_url,omitempty"`
+	// The landing page is on another site than the requested URL
+	HostChanged bool `json:"host_changed,omitempty"`
+	LinksCount  int  `json:"links_count"`
+	// links_internal.txt and links_external.txt line counts
+	InternalLinks int             `json:"internal_links"`
+	ExternalLinks int             `json:"external_links"`
+	Tags          []string        `json:"tags,omitempty"`
+	DOM           *domStats       `json:"dom,omitempty"`
+	Dimensions    *pageDimensions `json:"dimensions,omitempty"`
+	LoadTiming    *loadTiming     `json:"load_timing,omitempty"`
+	// "captcha" when an anti-bot challenge was served instead of the page
+	Blocked string         `json:"blocked,omitempty"`
+	Captcha *captchaReport `json:"captcha,omitempty"`
+	// Cookie consent banner, and whether -auto-accept-cookies got rid of it
+	Consent *consentReport `json:"consent,omitempty"`
+	// Login or paywall heuristics from -detect-gates
+	Gate *gateReport `json:"gate,omitempty"`
+	// Hosts whose responses were loaded despite a certificate error
+	RelaxedTLSHosts []string `json:"relaxed_tls_hosts,omitempty"`
+	// "static" when the page was fetched without a browser
+	Mode string `json:"mode,omitempty"`
+	// User agent of the browser that produced the capture
+	UserAgent string `json:"user_agent"`
+	// The page was captured in a visible browser after a blank headless load
+	HeadfulFallback bool `json:"headful_fallback,omitempty"`
+	// Scrolls that made the page taller with -auto-scroll
+	AutoScrolls int `json:"auto_scrolls,omitempty"`
+	// URLs of tabs the page opened, saved under new_targets/
+	NewTargets []string `json:"new_targets,omitempty"`
+	// Why the page was not captured, e.g. "thin"
+	Skipped string `json:"skipped,omitempty"`
+	// Why the capture stopped early, e.g. the -frame was not found
+	Error     string         `json:"error,omitempty"`
+	Resources *resourceUsage `json:"resources,omitempty"`
+	// SHA-256 of the visible text and of page.html
+	TextHash string `json:"text_hash,omitempty"`
+	HTMLHash string `json:"html_hash,omitempty"`
+	// Set once the page is compared with an earlier run
+	Changed      bool   `json:"changed"`
+	ComparedWith string `json:"compared_with,omitempty"`
+	// "text" or "html", what was compared
+	DiffMode string `json:"diff_mode,omitempty"`
+	// The run was cancelled (Ctrl-C) before the capture finished
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Bytes on the wire for the page and everything it loaded
+	BytesDownloaded int64 `json:"bytes_downloaded,omitempty"`
+	// Artifacts written into the run folder, relative to it
+	Files     []string `json:"files,omitempty"`
+	ElapsedMS int64    `json:"elapsed_ms"`
+	// Versions that produced the capture, for reproducing it
+	ChromedpVersion string `json:"chromedp_version,omitempty"`
+	BrowserVersion  string `json:"browser_version,omitempty"`
+}
+
+// Module version of chromedp compiled into this binary
+func chromedpVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/chromedp/chromedp" {
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// Minimal one line form of the manifest for NDJSON run ledgers
+type compactManifest struct {
+	URL        string `json:"url"`
+	Status     int64  `json:"status"`
+	LinksCount int    `json:"links_count"`
+	Changed    bool   `json:"changed"`
+}
+
+func (m *RunManifest) compact() compactManifest {
+	return compactManifest{
+		URL:        m.URL,
+		Status:     m.StatusCode,
+		LinksCount: m.LinksCount,
+		Changed:    m.Changed,
+	}
+}
+
+// Write the manifest as indented JSON into the run folder
+func writeManifest(out *outputDir, m *RunManifest) (string, error) {
+	return out.writeJSON("manifest.json", m)
+}
+
+// Write the compact manifest as a single line and append the same line
+// to the NDJSON index shared by all runs under baseDir
+func writeCompactManifest(out *outputDir, baseDir string, m *RunManifest) (string, error) {
+	line, err := json.Marshal(m.compact())
+	if err != nil {
+		return "", err
+	}
+	line = append(line, '\n')
+
+	savePath, err := out.writeFile("manifest.json", line)
+	if err != nil || savePath == "" {
+		return savePath, err
+	}
+
+	// O_APPEND keeps each run on its own line
+	index, err := os.OpenFile(filepath.Join(baseDir, "index.ndjson"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, out.filePerm)
+	if err != nil {
+		return savePath, err
+	}
+	defer index.Close()
+	_, err = index.Write(line)
+	return savePath, err
+}
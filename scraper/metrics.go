@@ -0,0 +1,111 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// One series of the metrics file
+type metric struct {
+	name, help, kind string
+	// Label pairs like result="ok", empty for none
+	labels string
+	value  float64
+}
+
+func (m metric) key() string {
+	if m.labels == "" {
+		return m.name
+	}
+	return m.name + "{" + m.labels + "}"
+}
+
+// Write Prometheus text format metrics for a batch to path, for
+// node_exporter's textfile collector. Counters add up across runs by
+// starting from the values already in the file; the last_run gauges only
+// describe this batch.
+func WriteMetrics(path string, summaries []PageSummary, elapsed time.Duration) error {
+	var ok, failed, skipped, links, httpErrors int
+	var downloaded int64
+	for _, s := range summaries {
+		switch {
+		case s.Err != nil:
+			failed++
+		case s.Skipped != "":
+			skipped++
+		default:
+			ok++
+		}
+		if s.StatusCode >= 400 {
+			httpErrors++
+		}
+		links += s.LinksCount
+		downloaded += s.BytesDownloaded
+	}
+
+	const pagesHelp = "Pages processed, by result."
+	metrics := []metric{
+		{"scrapper_pages_total", pagesHelp, "counter", `result="ok"`, float64(ok)},
+		{"scrapper_pages_total", pagesHelp, "counter", `result="failed"`, float64(failed)},
+		{"scrapper_pages_total", pagesHelp, "counter", `result="skipped"`, float64(skipped)},
+		{"scrapper_links_total", "Links extracted from scraped pages.", "counter", "", float64(links)},
+		{"scrapper_downloaded_bytes_total", "Bytes the browser transferred for pages and their resources.", "counter", "", float64(downloaded)},
+		{"scrapper_http_errors_total", "Pages that answered with a 4xx or 5xx status.", "counter", "", float64(httpErrors)},
+		{"scrapper_duration_seconds_total", "Time spent in scrape runs.", "counter", "", elapsed.Seconds()},
+		{"scrapper_last_run_pages", "Pages processed by the last run.", "gauge", "", float64(len(summaries))},
+		{"scrapper_last_run_duration_seconds", "Duration of the last run.", "gauge", "", elapsed.Seconds()},
+		{"scrapper_last_run_timestamp_seconds", "Unix time the last run finished.", "gauge", "", float64(time.Now().Unix())},
+	}
+
+	previous, err := readMetrics(path)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for i, m := range metrics {
+		if m.kind == "counter" {
+			m.value += previous[m.key()]
+		}
+		// HELP and TYPE once per metric name, before its first series
+		if i == 0 || metrics[i-1].name != m.name {
+			fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		}
+		fmt.Fprintf(&buf, "%s %s\n", m.key(), strconv.FormatFloat(m.value, 'f', -1, 64))
+	}
+
+	// Never half written, and readable by a collector running as another user
+	return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// Series values of an earlier metrics file; a missing file is no error
+func readMetrics(path string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return values, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(line[i+1:], 64); err == nil {
+			values[line[:i]] = v
+		}
+	}
+	return values, sc.Err()
+}
@@ -0,0 +1,51 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Turn what a user typed into a URL the browser can load. A missing
+// scheme means https, so example.com and example.com/path just work.
+func normalizeInputURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", errors.New("invalid URL: empty")
+	}
+	// about:blank, data: and the like have no "//" but are complete
+	if u, err := url.Parse(rawURL); err == nil && (u.Scheme == "about" || u.Scheme == "data") {
+		return rawURL, nil
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %v", err)
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Hostname() == "" {
+		return "", fmt.Errorf("invalid URL %q: no host", rawURL)
+	}
+	return u.String(), nil
+}
+
+// Plain words for the usual reasons a page can't be reached, so a typo in
+// the host doesn't read like a flaky network
+func navigationFailure(host string, err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "net::ERR_NAME_NOT_RESOLVED"), strings.Contains(msg, "net::ERR_NAME_RESOLUTION_FAILED"):
+		return fmt.Sprintf("DNS lookup failed for %s, check the host name", host)
+	case strings.Contains(msg, "net::ERR_CONNECTION_REFUSED"):
+		return fmt.Sprintf("connection refused by %s, nothing is listening on that port", host)
+	case errors.Is(err, context.DeadlineExceeded),
+		strings.Contains(msg, "net::ERR_CONNECTION_TIMED_OUT"), strings.Contains(msg, "net::ERR_TIMED_OUT"):
+		return fmt.Sprintf("timed out loading %s", host)
+	case strings.Contains(msg, "net::ERR_INTERNET_DISCONNECTED"), strings.Contains(msg, "net::ERR_ADDRESS_UNREACHABLE"):
+		return fmt.Sprintf("%s is unreachable from this network", host)
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// One response the page received, main document included
+type networkResponse struct {
+	URL      string `json:"url"`
+	Type     string `json:"type"`
+	Status   int64  `json:"status"`
+	MimeType string `json:"mime_type"`
+}
+
+// Every response of a tab, in arrival order, and the bytes transferred
+type responseLog struct {
+	mu        sync.Mutex
+	responses []networkResponse
+	bytes     float64
+}
+
+func watchResponses(ctx context.Context) *responseLog {
+	rl := &responseLog{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventResponseReceived:
+			rl.mu.Lock()
+			rl.responses = append(rl.responses, networkResponse{
+				URL:      ev.Response.URL,
+				Type:     ev.Type.String(),
+				Status:   ev.Response.Status,
+				MimeType: ev.Response.MimeType,
+			})
+			rl.mu.Unlock()
+		case *network.EventLoadingFinished:
+			// Size on the wire, headers and compression included
+			rl.mu.Lock()
+			rl.bytes += ev.EncodedDataLength
+			rl.mu.Unlock()
+		}
+	})
+	return rl
+}
+
+func (rl *responseLog) list() []networkResponse {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return append([]networkResponse{}, rl.responses...)
+}
+
+func (rl *responseLog) transferred() int64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return int64(rl.bytes)
+}
+
+// Status of a tab's main document, as the network listener last saw it
+type documentStatus struct {
+	mu   sync.Mutex
+	code int64
+	text string
+	// Landing host that got past a certificate error without being allowed to
+	insecure string
+}
+
+func (ds *documentStatus) set(code int64, text, insecure string) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.code, ds.text, ds.insecure = code, text, insecure
+}
+
+func (ds *documentStatus) get() (code int64, text, insecure string) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.code, ds.text, ds.insecure
+}
+
+// Body of a response as the server sent it, before any script ran
+func responseBody(ctx context.Context, id network.RequestID) ([]byte, error) {
+	if id == "" {
+		return nil, fmt.Errorf("no document request was seen")
+	}
+	var body []byte
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		body, err = network.GetResponseBody(id).Do(ctx)
+		return err
+	}))
+	return body, err
+}
+
+// "12 2xx, 1 3xx, 3 4xx, 0 5xx" for spotting broken assets at a glance
+func statusClassSummary(responses []networkResponse) string {
+	var classes [6]int
+	for _, r := range responses {
+		if c := r.Status / 100; c >= 1 && c <= 5 {
+			classes[c]++
+		}
+	}
+	return fmt.Sprintf("%d 2xx, %d 3xx, %d 4xx, %d 5xx", classes[2], classes[3], classes[4], classes[5])
+}
@@ -0,0 +1,68 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// Collects page targets (new tabs, popups, target=_blank) opened by the tab
+type targetCollector struct {
+	mu  sync.Mutex
+	ids []target.ID
+}
+
+// Start listening on the tab in ctx; must be called before navigating
+func watchNewTargets(ctx context.Context) *targetCollector {
+	tc := &targetCollector{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		created, ok := ev.(*target.EventTargetCreated)
+		if !ok || created.TargetInfo.Type != "page" {
+			return
+		}
+		if c := chromedp.FromContext(ctx); c.Target == nil || created.TargetInfo.OpenerID != c.Target.TargetID {
+			return
+		}
+		tc.mu.Lock()
+		tc.ids = append(tc.ids, created.TargetInfo.TargetID)
+		tc.mu.Unlock()
+	})
+	return tc
+}
+
+func (tc *targetCollector) list() []target.ID {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return append([]target.ID(nil), tc.ids...)
+}
+
+// What was captured from one opened target
+type targetCapture struct {
+	URL   string
+	HTML  string
+	Links []pageLink
+}
+
+// Attach to a target, capture it and close it again
+func scrapeNewTarget(ctx context.Context, lg *logger, id target.ID, pierceShadow bool) (*targetCapture, error) {
+	tabCtx, cancel := chromedp.NewContext(ctx, chromedp.WithTargetID(id))
+	// Cancelling the attached context detaches and closes the tab
+	defer cancel()
+
+	capture := &targetCapture{}
+	if err := chromedp.Run(tabCtx, chromedp.WaitReady("body"), chromedp.Location(&capture.URL)); err != nil {
+		return nil, fmt.Errorf("error attaching to new target: %v", err)
+	}
+	html, err := contentRetrieval(tabCtx, lg, "")
+	if err != nil {
+		return nil, err
+	}
+	capture.HTML = html
+	if capture.Links, err = extractLinks(tabCtx, lg, pierceShadow); err != nil {
+		return nil, err
+	}
+	return capture, nil
+}
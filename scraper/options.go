@@ -0,0 +1,279 @@
+package scraper
+
+import (
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// What a Scraper captures and where it saves it, the CLI fills this from
+// its flags
+type Options struct {
+	// How much the Scraper prints, progress and errors by default, and
+	// where to; os.Stderr when nil
+	LogLevel  LogLevel
+	LogOutput io.Writer
+	// Base output directory, and whether runs skip their own subfolder
+	Out  string
+	Flat bool
+	// Deadline for the whole page load and capture
+	Timeout time.Duration
+	// TLS is only relaxed for these hosts when strict mode is on. Chrome's
+	// override is browser wide, so subresources of other hosts loaded by an
+	// allowed page skip the check as well; a landing page on another host
+	// fails.
+	InsecureHosts          []string
+	AllowInsecureLocalhost bool
+	// Save <noscript> fallbacks into noscript.html
+	Noscript bool
+	// JSON file mapping tag names to keyword/selector rules
+	TagRules string
+	// Write a one line manifest and append it to <out>/index.ndjson
+	CompactManifest bool
+	// Save the breadcrumb trail into breadcrumbs.json
+	Breadcrumbs bool
+	// Replace inline base64 images with placeholders before saving page.html
+	StripDataURIs bool
+	SaveDataURIs  bool
+	// Wait until at most WaitIdleRequests requests have been in flight for
+	// WaitIdleQuiet, giving up after WaitIdleTimeout
+	WaitIdle         bool
+	WaitIdleRequests int
+	WaitIdleQuiet    time.Duration
+	WaitIdleTimeout  time.Duration
+	// Wait until elements matching this selector are gone before capture
+	WaitGone        string
+	WaitGoneTimeout time.Duration
+	// Wait until at least waitCountN elements match this selector
+	WaitCountSelector string
+	WaitCountN        int
+	WaitCountTimeout  time.Duration
+	// Fail the page unless this selector becomes visible
+	WaitFor        string
+	WaitForTimeout time.Duration
+	// Record DOM size and depth in the manifest
+	DOMStats bool
+	// name=selector pairs whose every match goes into fields.json
+	SelectAll []FieldSelector
+	// Retry once in a visible browser when the headless page is blank
+	AutoHeadfulFallback bool
+	// Save current/total page and next/prev URLs into pagination.json
+	Pagination bool
+	// Screenshots and PDFs allowed to run at the same time, 0 is unlimited
+	MaxConcurrentScreenshots int
+	// Walk open shadow roots when extracting
+	PierceShadow bool
+	// SQLite database receiving one row per canonical URL
+	SQLite  string
+	NoFiles bool
+	// Write every run of a host to <out>/<host>/, replacing the files of
+	// the previous run; anything else in the folder is removed when the
+	// run is done
+	NoTimestampFolder bool
+	// Nest runs as <out>/<host>/<timestamp>/
+	GroupByHost bool
+	// Also save the server's HTML response as raw.html
+	RawHTML bool
+	// raw, pretty or minify for the saved page.html
+	HTMLFormat string
+	// Save the main article as article.html and article.txt
+	Readability bool
+	// Rotate through userAgents while the page answers with a block status
+	RetryDifferentUA bool
+	// Headings, paragraphs and lists as JSON blocks
+	StructuredText bool
+	// Capture tabs the page opens (target=_blank, window.open)
+	FollowNewTargets bool
+	// Save every form's action, method and field names into forms.json
+	Forms bool
+	// Save canonical and hreflang alternates into hreflang.json
+	Hreflang bool
+	// JavaScript file run after navigation, before capture
+	EvalAfter     string
+	EvalAfterWait time.Duration
+	// Skip service worker caches so PWAs are fetched fresh
+	BypassServiceWorker bool
+	// Append the run ID to the run folder name
+	RunIDInName bool
+	// Pages with less visible text are recorded but not saved
+	MinTextLength int
+	// How many of the slowest requests go to slow_requests.json
+	SlowRequests int
+	// Load the page and report its status without saving anything
+	CheckOnly bool
+	// Try plain HTTP before starting a browser, just for links.txt and metadata.json
+	StaticLinks bool
+	// Scroll to the bottom until the page stops growing, at most
+	// AutoScrollMax times with AutoScrollPause between scrolls
+	AutoScroll      bool
+	AutoScrollMax   int
+	AutoScrollPause time.Duration
+	// First element matching this selector is captured into element.png
+	ScreenshotSelector string
+	// Selector or pixel offset to scroll to for scrolled.png
+	ScrollTo string
+	// Click the accept button of cookie consent banners before capture
+	AutoAcceptCookies bool
+	// Flag login/paywall gated pages in the manifest
+	DetectGates bool
+	// Save error.png when navigation or a wait fails
+	ScreenshotOnError bool
+	// The screenshot covers the full page or just the viewport
+	ScreenshotMode string
+	// png (screenshot.png) or jpeg (screenshot.jpg) at ScreenshotQuality
+	ScreenshotFormat  string
+	ScreenshotQuality int
+	// Limit for capturing the screenshot alone, 0 leaves only Timeout
+	ScreenshotTimeout time.Duration
+	// Print one JSON document to stdout instead of writing files, it
+	// implies NoFiles
+	JSONOutput     bool
+	JSONScreenshot bool
+	JSONText       bool
+	// Where JSONOutput documents go, os.Stdout when nil
+	JSONWriter io.Writer
+	// Keys typed into elements before capture
+	KeyInputs []KeyInput
+	// Elements to move the mouse over before capture
+	Hover []string
+	// Save the rendered DOM as a JSON tree in dom.json
+	DOMJSON bool
+	// Save prices with currency into prices.json
+	ExtractPrices bool
+	// Save JSON-LD blocks and microdata items into structured_data.json
+	StructuredData bool
+	// Iframe (selector or URL) that page.html and links.txt come from
+	Frame string
+	// Only this element's outer HTML goes into page.html
+	Selector string
+	// Go text/template rendered into report.<templateExt>
+	Template    string
+	TemplateExt string
+	// Also print the page to page.pdf
+	PDF bool
+	// Save console messages and uncaught JS errors into console.log
+	CaptureConsole bool
+	// Request every extracted link and save the answers to link_status.csv
+	CheckLinks bool
+	// links.<linksFormat>: txt, json or csv
+	LinksFormat string
+	// Save javascript:, mailto: and tel: links into pseudo_links.txt, they
+	// are left out of links.txt either way
+	PseudoLinks bool
+	// links.txt normalization
+	KeepFragments      bool
+	StripTrailingSlash bool
+	// Count blog.x.com as internal when scraping x.com
+	IncludeSubdomains bool
+	// Route the browser through this proxy, optionally with credentials
+	Proxy     string
+	ProxyUser string
+	ProxyPass string
+	// HTTP Basic (or Digest) credentials, only answered for challenges from
+	// the scraped page's own host
+	BasicAuthUser string
+	BasicAuthPass string
+	// Chrome profile kept across runs (cookies, localStorage, logins).
+	// Chrome locks it, so only one browser at a time may use it.
+	UserDataDir string
+	// Chrome or Chromium binary to start instead of the one found on PATH
+	ChromePath string
+	// Show the browser window instead of running headless
+	Headful bool
+	// Scrape even where robots.txt disallows it
+	IgnoreRobots bool
+	// Follow same-host links this many levels deep, at most MaxPages pages
+	// including the start URLs (0 is no limit)
+	Depth    int
+	MaxPages int
+	// crawl_state.json of an interrupted crawl to continue, it is kept up
+	// to date instead of <out>/crawl_state.json
+	Resume string
+	// Pages scraped at the same time, each in a tab of the crawl's browser
+	Concurrency int
+	// Lower the concurrency while pages answer 429 or time out, down to
+	// AdaptiveMinConcurrency (0 is 1), and raise it again on successes
+	AdaptiveConcurrency    bool
+	AdaptiveMinConcurrency int
+	// Concurrency is lowered to what fits this many open files; 0 reads
+	// RLIMIT_NOFILE where there is one, negative skips the check
+	MaxFileDescriptors int
+	// Minimum time between two navigations to the same host
+	Delay time.Duration
+	// Extra navigation attempts after 5xx answers, timeouts and network errors
+	Retries int
+	// JSON file of cookies installed before navigating
+	Cookies string
+	// Start every page without the cookies earlier pages of its host ended
+	// with
+	IsolateCookies bool
+	// Extra request headers, e.g. Authorization, for every browser request
+	Headers map[string]string
+	// Replaces the built-in browser user agent
+	UserAgent string
+	// Browser window size, the viewport pages are laid out in
+	Width  int
+	Height int
+	// Emulated phone or tablet instead of the desktop window
+	Device *DeviceProfile
+	// Save the readable text as text.txt
+	Text bool
+	// Earlier run folder the page is compared with, by its visible text
+	// or with DiffMode "html" by page.html ("" is "text")
+	Compare  string
+	DiffMode string
+	// Save offline.html with stylesheets, images and fonts in assets/
+	SelfContained bool
+	// Save every <img> into images/
+	DownloadImages bool
+	// Images and offline assets downloaded at once, across all pages of
+	// the run; 0 is 4
+	DownloadConcurrency int
+	// page.html, screenshots, page.pdf and images over this many bytes are
+	// skipped, 0 is no limit
+	MaxFileSize int64
+	// Modes for created folders and files, 0 keeps 0755 and 0644. The
+	// umask still applies.
+	DirPerm  os.FileMode
+	FilePerm os.FileMode
+	// text/template for the run folder name with .Host, .Timestamp and
+	// .URLHash, instead of <timestamp>_<host>
+	NameTemplate string
+}
+
+// 0755 -> rwxr-xr-x unless DirPerm says otherwise
+func (o *Options) dirMode() os.FileMode {
+	if o.DirPerm == 0 {
+		return 0755
+	}
+	return o.DirPerm
+}
+
+// 0644 -> rw-r--r-- unless FilePerm says otherwise
+func (o *Options) fileMode() os.FileMode {
+	if o.FilePerm == 0 {
+		return 0644
+	}
+	return o.FilePerm
+}
+
+// Reports whether host may be loaded despite a certificate error in
+// strict mode
+func (o *Options) relaxedTLSAllowed(host string) bool {
+	if hostAllowed(host, o.InsecureHosts) {
+		return true
+	}
+	if !o.AllowInsecureLocalhost {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return strings.EqualFold(host, "localhost") || (ip != nil && ip.IsLoopback())
+}
+
+// strictTLS reports whether certificate errors are checked per host
+// instead of being ignored for every site.
+func (o *Options) strictTLS() bool {
+	return o.AllowInsecureLocalhost || len(o.InsecureHosts) > 0
+}
@@ -0,0 +1,280 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Run folder that every artifact is written into. When disabled nothing
+// touches the disk and writes return an empty path.
+type outputDir struct {
+	path     string
+	disabled bool
+	// Cap for the files that can get huge, 0 is no limit
+	maxFileSize int64
+	// Modes for new folders and files
+	dirPerm, filePerm os.FileMode
+
+	// Relative names of everything written so far, for the manifest
+	mu    sync.Mutex
+	files []string
+}
+
+func (d *outputDir) create() error {
+	if d.disabled {
+		return nil
+	}
+	return os.MkdirAll(d.path, d.dirPerm)
+}
+
+// Reports whether the run folder is already on disk
+func (d *outputDir) exists() bool {
+	if d.disabled {
+		return false
+	}
+	_, err := os.Stat(d.path)
+	return err == nil
+}
+
+// Write a file relative to the run folder, creating subfolders as needed
+func (d *outputDir) writeFile(name string, data []byte) (string, error) {
+	if d.disabled {
+		return "", nil
+	}
+	savePath := filepath.Join(d.path, name)
+	if err := os.MkdirAll(filepath.Dir(savePath), d.dirPerm); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(savePath, data, d.filePerm); err != nil {
+		return savePath, err
+	}
+	d.mu.Lock()
+	d.files = append(d.files, filepath.ToSlash(name))
+	d.mu.Unlock()
+	return savePath, nil
+}
+
+// Count a file an earlier run wrote as written by this one, for a download
+// the server reported unchanged
+func (d *outputDir) keep(name string) {
+	if d.disabled {
+		return
+	}
+	d.mu.Lock()
+	d.files = append(d.files, filepath.ToSlash(name))
+	d.mu.Unlock()
+}
+
+// writeFile for page.html, screenshots, PDFs and images: anything over
+// maxFileSize is skipped with an error instead of filling the disk
+func (d *outputDir) writeCapped(name string, data []byte) (string, error) {
+	if d.maxFileSize > 0 && int64(len(data)) > d.maxFileSize {
+		return "", fmt.Errorf("%s is %d bytes, over the %d byte limit, not saved", name, len(data), d.maxFileSize)
+	}
+	return d.writeFile(name, data)
+}
+
+// Remove every file in the folder this run didn't write, once it wrote
+// all of its own, so files of an earlier run (error.png, diff.txt) don't
+// linger. Files changed since the run started are left alone, they belong
+// to another page of the crawl sharing the folder. Folders emptied on the
+// way go too.
+func (d *outputDir) removeStale(lg *logger, started time.Time) {
+	if d.disabled {
+		return
+	}
+	keep := make(map[string]bool)
+	for _, name := range d.written() {
+		keep[name] = true
+	}
+	filepath.WalkDir(d.path, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(d.path, p)
+		if err != nil || keep[filepath.ToSlash(rel)] {
+			return nil
+		}
+		if info, err := entry.Info(); err != nil || !info.ModTime().Before(started) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			lg.Printf("Failed to remove %s of the previous run: %v\n", p, err)
+			return nil
+		}
+		// Fails for folders that still hold something
+		for dir := filepath.Dir(p); dir != d.path && insideDir(d.path, dir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Files written into the run folder, sorted
+func (d *outputDir) written() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	files := append([]string{}, d.files...)
+	sort.Strings(files)
+	return files
+}
+
+// Save any value as indented JSON in the run folder
+func (d *outputDir) writeJSON(name string, v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return d.writeFile(name, data)
+}
+
+// Byte size suffixes, longest first so "MB" is not read as "B"
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+// Parse a size like 500000, 512KB or 1.5MB (binary units, case
+// insensitive)
+func ParseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	unit := 1.0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected bytes or a number with KB, MB or GB", value)
+	}
+	return int64(n * unit), nil
+}
+
+// Host names become folder names, so keep only characters that are safe
+// on every filesystem and can't climb out of the base directory
+func sanitizeHost(host string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(host) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name := strings.Trim(b.String(), ".")
+	if name == "" {
+		return "unknown-host"
+	}
+	return name
+}
+
+// Folder of one run: <base>/<timestamp>_<host> or <base>/<host>/<timestamp>
+func runFolderPath(baseDir, host, timestamp string, groupByHost bool) string {
+	host = sanitizeHost(host)
+	if groupByHost {
+		return filepath.Join(baseDir, host, timestamp)
+	}
+	return filepath.Join(baseDir, fmt.Sprintf("%s_%s", timestamp, host))
+}
+
+// Replace path in one rename, so a crash mid-write leaves the old file
+// instead of half of the new one
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes the file 0600, readers may run as another user
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Parse an octal permission like 750 or 0640 for -dir-perm and -file-perm
+func ParsePerm(value string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(strings.TrimPrefix(value, "0o"), 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("%q is not an octal permission between 0 and 0777", value)
+	}
+	return os.FileMode(perm), nil
+}
+
+// Values a -name-template can use
+type folderNameData struct {
+	Host      string
+	Timestamp string
+	URLHash   string
+}
+
+// Short hash of the page URL, the same URL always gets the same one
+func urlHash(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Parse -name-template and render it once with sample values, so a typo
+// in a field name fails before any folder is created
+func parseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name-template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := folderNameData{Host: "example.com", Timestamp: "2006-01-02_15-04-05", URLHash: urlHash("https://example.com/")}
+	if _, err := renderFolderName(tmpl, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Folder name from the -name-template, relative to the output directory;
+// slashes in it nest folders
+func renderFolderName(tmpl *template.Template, data folderNameData) (string, error) {
+	data.Host = sanitizeHost(data.Host)
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	name := filepath.Clean(filepath.FromSlash(strings.TrimSpace(b.String())))
+	if name == "." || name == "" {
+		return "", fmt.Errorf("the template renders an empty folder name")
+	}
+	if !insideDir(".", name) {
+		return "", fmt.Errorf("folder name %s leaves the output directory", name)
+	}
+	return name, nil
+}
+
+// Reports whether path is dir or below it; a safety net on top of
+// sanitizeHost for the run folder
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,172 @@
+package scraper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoveStale(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"page.html", "error.png", "images/a.png", "images/old.png", "new_targets/1/page.html"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("previous run"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	started := time.Now().Add(-time.Minute)
+	out := &outputDir{path: dir, dirPerm: 0755, filePerm: 0644}
+	for _, name := range []string{"page.html", "images/a.png", "manifest.json"} {
+		if _, err := out.writeFile(name, []byte("this run")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another page of the crawl writing into the same folder
+	if err := os.WriteFile(filepath.Join(dir, "text.txt"), []byte("other page"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.removeStale(newLogger(testWriter{t}, LevelInfo), started)
+
+	for name, want := range map[string]bool{
+		"page.html":      true,
+		"images/a.png":   true,
+		"manifest.json":  true,
+		"text.txt":       true,
+		"error.png":      false,
+		"images/old.png": false,
+		"new_targets":    false,
+	} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", name, exists, want)
+		}
+	}
+}
+
+// A compact manifest lists no files, the second run still cleans up
+func TestNoTimestampFolderRemovesStaleFilesWithCompactManifest(t *testing.T) {
+	srv := crawlFixture(t)
+	o := staticCrawlOptions(t)
+	o.NoTimestampFolder = true
+	o.CompactManifest = true
+
+	scrape := func(pseudo bool) *Result {
+		o.PseudoLinks = pseudo
+		s, err := New(o)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		defer s.Close()
+		result, err := s.Scrape(context.Background(), srv.URL+"/")
+		if err != nil {
+			t.Fatalf("Scrape: %v", err)
+		}
+		return result
+	}
+	first := scrape(true)
+	stale := filepath.Join(first.Dir, "pseudo_links.txt")
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	// The second run must see it as older than its start
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	second := scrape(false)
+	if second.Dir != first.Dir {
+		t.Fatalf("runs used %s and %s, want one folder", first.Dir, second.Dir)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("pseudo_links.txt of the first run is still there (%v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(second.Dir, "links.txt")); err != nil {
+		t.Errorf("links.txt of the second run: %v", err)
+	}
+}
+
+func TestSanitizeHost(t *testing.T) {
+	tests := []struct{ host, want string }{
+		{"Example.COM", "example.com"},
+		{"example.com:8080", "example.com_8080"},
+		{"[::1]:80", "___1__80"},
+		{"../../etc", "_.._etc"},
+		{"...", "unknown-host"},
+		{"", "unknown-host"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeHost(tt.host); got != tt.want {
+			t.Errorf("sanitizeHost(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestRunFolderPath(t *testing.T) {
+	tests := []struct {
+		host        string
+		groupByHost bool
+		want        string
+	}{
+		{"Example.com", false, filepath.Join("out", "2006-01-02_15-04-05_example.com")},
+		{"Example.com", true, filepath.Join("out", "example.com", "2006-01-02_15-04-05")},
+		{"../x", true, filepath.Join("out", "_x", "2006-01-02_15-04-05")},
+	}
+	for _, tt := range tests {
+		if got := runFolderPath("out", tt.host, "2006-01-02_15-04-05", tt.groupByHost); got != tt.want {
+			t.Errorf("runFolderPath(%q, %v) = %q, want %q", tt.host, tt.groupByHost, got, tt.want)
+		}
+	}
+}
+
+func TestRenderFolderName(t *testing.T) {
+	data := folderNameData{Host: "Example.com:8080", Timestamp: "2006-01-02_15-04-05", URLHash: "abc123"}
+	tests := []struct {
+		name, template, want string
+		wantErr              bool
+	}{
+		{"fields", "{{.Host}}-{{.URLHash}}", "example.com_8080-abc123", false},
+		{"slashes nest folders", "{{.Host}}/{{.Timestamp}}", filepath.Join("example.com_8080", "2006-01-02_15-04-05"), false},
+		{"cleaned", " a//b/./c ", filepath.Join("a", "b", "c"), false},
+		{"empty", "   ", "", true},
+		{"climbs out", "../{{.Host}}", "", true},
+		{"climbs out after cleaning", "a/../../b", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseNameTemplate(tt.template)
+			if err != nil {
+				if !tt.wantErr {
+					t.Fatalf("parseNameTemplate: %v", err)
+				}
+				return
+			}
+			got, err := renderFolderName(tmpl, data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("renderFolderName = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("renderFolderName = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNameTemplateRejectsUnknownFields(t *testing.T) {
+	if _, err := parseNameTemplate("{{.Hostname}}"); err == nil {
+		t.Error("parseNameTemplate accepted an unknown field")
+	}
+}
@@ -0,0 +1,95 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Price found on the page. Source is json-ld, microdata, meta or text,
+// text matches are a regex guess and the least reliable.
+type price struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency,omitempty"`
+	Source   string `json:"source"`
+	Context  string `json:"context,omitempty"`
+}
+
+// Structured sources run first; the visible text is only scanned when
+// none of them found anything. Duplicates (same amount and currency) keep
+// the first, most structured, source.
+const extractPricesJS = `(() => {
+	const prices = [];
+	const seen = new Set();
+	const num = (v) => String(v == null ? '' : v).replace(/[^\d.,-]/g, '');
+	const add = (amount, currency, source, context) => {
+		amount = num(amount);
+		if (!amount || !/\d/.test(amount)) return;
+		currency = String(currency || '').trim().toUpperCase();
+		const key = amount.replace(/,/g, '') + '|' + currency;
+		if (seen.has(key)) return;
+		seen.add(key);
+		prices.push({ amount, currency, source, context: String(context || '').trim().slice(0, 120) });
+	};
+
+	// JSON-LD Offer / AggregateOffer / PriceSpecification
+	const walk = (node, name) => {
+		if (Array.isArray(node)) { node.forEach((n) => walk(n, name)); return; }
+		if (!node || typeof node !== 'object') return;
+		name = node.name || name;
+		const currency = node.priceCurrency;
+		for (const key of ['price', 'lowPrice', 'highPrice']) {
+			if (node[key] != null && typeof node[key] !== 'object') add(node[key], currency, 'json-ld', name);
+		}
+		for (const key of ['offers', 'priceSpecification', '@graph', 'itemListElement', 'item']) {
+			if (node[key]) walk(node[key], name);
+		}
+	};
+	for (const s of document.querySelectorAll('script[type="application/ld+json"]')) {
+		try { walk(JSON.parse(s.textContent)); } catch (e) {}
+	}
+
+	// Microdata itemprop="price"
+	for (const el of document.querySelectorAll('[itemprop="price"], [itemprop="lowPrice"], [itemprop="highPrice"]')) {
+		const scope = el.closest('[itemscope]') || document;
+		const cur = scope.querySelector('[itemprop="priceCurrency"]');
+		const currency = cur ? (cur.getAttribute('content') || cur.textContent) : '';
+		add(el.getAttribute('content') || el.textContent, currency, 'microdata', el.textContent);
+	}
+
+	// Open Graph / product meta tags
+	const meta = (p) => {
+		const el = document.querySelector('meta[property="' + p + '"], meta[name="' + p + '"]');
+		return el ? el.getAttribute('content') : '';
+	};
+	for (const prefix of ['product:price', 'og:price']) {
+		if (meta(prefix + ':amount')) add(meta(prefix + ':amount'), meta(prefix + ':currency'), 'meta', prefix);
+	}
+
+	if (prices.length) return prices;
+
+	// Visible text: a currency symbol or code next to a number
+	const symbols = { '$': 'USD', '€': 'EUR', '£': 'GBP', '¥': 'JPY', '₺': 'TRY', '₹': 'INR', '₩': 'KRW' };
+	const pattern = /(?:([$€£¥₺₹₩]|\b(?:USD|EUR|GBP|TRY|JPY|CHF|CAD|AUD|INR)\b)\s?(\d{1,3}(?:[.,\s]\d{3})*(?:[.,]\d{1,2})?))|(?:(\d{1,3}(?:[.,\s]\d{3})*(?:[.,]\d{1,2})?)\s?([$€£¥₺₹₩]|\b(?:USD|EUR|GBP|TRY|JPY|CHF|CAD|AUD|INR|TL)\b))/g;
+	const text = document.body ? document.body.innerText : '';
+	for (const m of text.matchAll(pattern)) {
+		const cur = m[1] || m[4];
+		const amount = (m[2] || m[3]).replace(/\s/g, '');
+		const currency = symbols[cur] || (cur === 'TL' ? 'TRY' : cur);
+		add(amount, currency, 'text', text.slice(Math.max(0, m.index - 40), m.index + m[0].length + 20).replace(/\s+/g, ' '));
+	}
+	return prices;
+})()`
+
+// Best effort: markup varies wildly, so treat the result as a hint
+func extractPrices(ctx context.Context) ([]price, error) {
+	var prices []price
+	if err := chromedp.Run(ctx, chromedp.Evaluate(extractPricesJS, &prices)); err != nil {
+		return nil, fmt.Errorf("error extracting prices: %v", err)
+	}
+	if prices == nil {
+		prices = []price{}
+	}
+	return prices, nil
+}
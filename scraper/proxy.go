@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+)
+
+// Chrome takes http, https and socks5 proxies. An address that parses but
+// doesn't answer shows up as a navigation error (ERR_PROXY_CONNECTION_FAILED),
+// not as a hang.
+func ValidateProxy(addr string) error {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid -proxy %q, expected scheme://host:port", addr)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	default:
+		return fmt.Errorf("unsupported -proxy scheme %q, expected http, https or socks5", u.Scheme)
+	}
+}
+
+// Parse -proxy-auth user:pass
+func ParseProxyAuth(value string) (user, pass string, err error) {
+	return parseCredentials("-proxy-auth", value)
+}
+
+// Parse -basic-auth user:pass
+func ParseBasicAuth(value string) (user, pass string, err error) {
+	return parseCredentials("-basic-auth", value)
+}
+
+// The value is never part of the error, it holds a password
+func parseCredentials(flagName, value string) (user, pass string, err error) {
+	user, pass, ok := strings.Cut(value, ":")
+	if !ok || user == "" {
+		return "", "", fmt.Errorf("invalid %s, expected user:pass", flagName)
+	}
+	return user, pass, nil
+}
+
+// Credentials for the auth challenges of one page
+type authConfig struct {
+	proxyUser, proxyPass string
+	// HTTP auth, only ever given to the page's own host
+	host, user, pass string
+}
+
+// Answer authentication challenges through the Fetch domain. Every
+// request is paused and resumed right away. Proxy challenges get the proxy
+// credentials; server challenges get the basic auth credentials when they
+// come from the page's host, anything else is left to the browser. A
+// challenge repeated for the same request means the credentials were
+// rejected, so it is cancelled instead of looping.
+func enableAuth(ctx context.Context, lg *logger, auth authConfig) error {
+	var mu sync.Mutex
+	tried := make(map[fetch.RequestID]bool)
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() {
+				if err := chromedp.Run(ctx, fetch.ContinueRequest(ev.RequestID)); err != nil && ctx.Err() == nil {
+					lg.Printf("Failed to continue request: %v\n", err)
+				}
+			}()
+		case *fetch.EventAuthRequired:
+			response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+			user, pass, flagName := "", "", ""
+			if ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+				user, pass, flagName = auth.proxyUser, auth.proxyPass, "-proxy-auth"
+			} else if auth.user != "" && challengeFromHost(ev.AuthChallenge.Origin, auth.host) {
+				user, pass, flagName = auth.user, auth.pass, "-basic-auth"
+			}
+			if user != "" {
+				mu.Lock()
+				retried := tried[ev.RequestID]
+				tried[ev.RequestID] = true
+				mu.Unlock()
+				if retried {
+					lg.Printf("%s rejected the %s credentials\n", ev.AuthChallenge.Origin, flagName)
+					response.Response = fetch.AuthChallengeResponseResponseCancelAuth
+				} else {
+					response.Response = fetch.AuthChallengeResponseResponseProvideCredentials
+					response.Username = user
+					response.Password = pass
+				}
+			}
+			go func() {
+				if err := chromedp.Run(ctx, fetch.ContinueWithAuth(ev.RequestID, response)); err != nil && ctx.Err() == nil {
+					lg.Printf("Failed to answer auth challenge: %v\n", err)
+				}
+			}()
+		}
+	})
+	return chromedp.Run(ctx, fetch.Enable().WithHandleAuthRequests(true))
+}
+
+// Origin is scheme://host[:port] of whoever asked for credentials
+func challengeFromHost(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Hostname(), host)
+}
+
+// Client for the requests made without the browser, sent through the same
+// proxy as the browser. It keeps up to conns idle connections per host, so
+// parallel downloads reuse them instead of dialing every time.
+func newStaticClient(addr, user, pass string, conns int) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = max(conns, 2)
+	if addr != "" {
+		u, _ := url.Parse(addr)
+		if user != "" {
+			u.User = url.UserPassword(user, pass)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+}
@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Main content block picked by the readability heuristic
+type article struct {
+	Title string `json:"title"`
+	HTML  string `json:"html"`
+	Text  string `json:"text"`
+	Score int    `json:"score"`
+}
+
+// Scores paragraphs by text length and commas, hands the score up to the
+// parent (full) and grandparent (half), and adjusts for class/id hints and
+// link density, much like Arc90's readability
+const readabilityJS = `(() => {
+	const positive = /article|body|content|entry|main|page|post|text|blog|story/i;
+	const negative = /comment|footer|foot|nav|sidebar|side|ad-|ads|sponsor|share|social|menu|header|masthead|promo|related|banner|cookie|popup|modal/i;
+	const classWeight = (el) => {
+		let w = 0;
+		const hint = (el.className && typeof el.className === 'string' ? el.className : '') + ' ' + el.id;
+		if (negative.test(hint)) w -= 25;
+		if (positive.test(hint)) w += 25;
+		return w;
+	};
+	const linkDensity = (el) => {
+		const total = el.innerText.length || 1;
+		let links = 0;
+		for (const a of el.querySelectorAll('a')) links += a.innerText.length;
+		return links / total;
+	};
+
+	const scores = new Map();
+	const addScore = (el, value) => {
+		if (!el || el === document.body.parentElement) return;
+		if (!scores.has(el)) {
+			let base = classWeight(el);
+			if (/^(ARTICLE|MAIN)$/.test(el.tagName)) base += 10;
+			if (/^(DIV|SECTION)$/.test(el.tagName)) base += 5;
+			scores.set(el, base);
+		}
+		scores.set(el, scores.get(el) + value);
+	};
+
+	for (const p of document.querySelectorAll('p, pre, td, blockquote')) {
+		const text = p.innerText.trim();
+		if (text.length < 25) continue;
+		const score = 1 + text.split(',').length + Math.min(Math.floor(text.length / 100), 3);
+		addScore(p.parentElement, score);
+		addScore(p.parentElement && p.parentElement.parentElement, score / 2);
+	}
+
+	let best = null, bestScore = 0;
+	for (const [el, score] of scores) {
+		const adjusted = score * (1 - linkDensity(el));
+		if (adjusted > bestScore) { best = el; bestScore = adjusted; }
+	}
+	if (!best) best = document.querySelector('article, main') || document.body;
+
+	const clone = best.cloneNode(true);
+	for (const junk of clone.querySelectorAll('script, style, noscript, nav, aside, form, iframe, footer, button')) junk.remove();
+	return { title: document.title, html: clone.innerHTML, text: best.innerText.trim(), score: Math.round(bestScore) };
+})()`
+
+func extractArticle(ctx context.Context) (*article, error) {
+	var a article
+	if err := chromedp.Run(ctx, chromedp.Evaluate(readabilityJS, &a)); err != nil {
+		return nil, fmt.Errorf("error extracting article: %v", err)
+	}
+	return &a, nil
+}
+
+// Standalone document around the article body
+func (a *article) document() string {
+	title := html.EscapeString(a.Title)
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + title + "</title>\n</head>\n<body>\n<article>\n" +
+		a.HTML + "\n</article>\n</body>\n</html>\n"
+}
@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// One step of the main document's redirect chain
+type redirectHop struct {
+	URL    string `json:"url"`
+	Status int64  `json:"status"`
+}
+
+// HTTP redirects of the first document request of a tab. Every redirect
+// is reported as a new requestWillBeSent with the same request ID and the
+// 3xx answer in RedirectResponse.
+type redirectWatcher struct {
+	mu        sync.Mutex
+	requestID network.RequestID
+	hops      []redirectHop
+	final     string
+}
+
+func watchRedirects(ctx context.Context) *redirectWatcher {
+	rw := &redirectWatcher{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		req, ok := ev.(*network.EventRequestWillBeSent)
+		if !ok || req.Type != network.ResourceTypeDocument {
+			return
+		}
+		rw.mu.Lock()
+		defer rw.mu.Unlock()
+		if rw.requestID == "" {
+			rw.requestID = req.RequestID
+		} else if req.RequestID != rw.requestID {
+			return
+		}
+		if req.RedirectResponse != nil {
+			rw.hops = append(rw.hops, redirectHop{URL: req.RedirectResponse.URL, Status: req.RedirectResponse.Status})
+		}
+		rw.final = req.Request.URL
+	})
+	return rw
+}
+
+// Request ID of the main document, its final response carries the body
+func (rw *redirectWatcher) documentRequest() network.RequestID {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.requestID
+}
+
+// Requested URL to landing page, ending with the final status; nil when
+// the page did not redirect
+func (rw *redirectWatcher) chain(finalStatus int64) []redirectHop {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if len(rw.hops) == 0 {
+		return nil
+	}
+	chain := append([]redirectHop{}, rw.hops...)
+	return append(chain, redirectHop{URL: rw.final, Status: finalStatus})
+}
+
+// "http://a (301) -> https://a (200)"
+func redirectSummary(chain []redirectHop) string {
+	steps := make([]string, len(chain))
+	for i, hop := range chain {
+		steps[i] = fmt.Sprintf("%s (%d)", hop.URL, hop.Status)
+	}
+	return strings.Join(steps, " -> ")
+}
+
+// Whether the landing page is on another site than the one requested.
+// example.com to www.example.com is the usual canonical redirect, not a
+// different site.
+func redirectChangedHost(requested, final string) bool {
+	from, err := url.Parse(requested)
+	if err != nil {
+		return false
+	}
+	to, err := url.Parse(final)
+	if err != nil {
+		return false
+	}
+	trim := func(h string) string { return strings.TrimPrefix(strings.ToLower(h), "www.") }
+	return trim(from.Hostname()) != trim(to.Hostname())
+}
@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Best-effort resource usage of the run. Fields that can't be measured on
+// the current platform stay zero. Pages of a crawl share one browser, so
+// the Chrome numbers cover every tab open at the time.
+type resourceUsage struct {
+	GoSysBytes         uint64  `json:"go_sys_bytes"`
+	ProcessMaxRSSBytes int64   `json:"process_max_rss_bytes,omitempty"`
+	ProcessCPUSeconds  float64 `json:"process_cpu_seconds,omitempty"`
+	ChromeProcesses    int     `json:"chrome_processes,omitempty"`
+	ChromePeakRSSBytes int64   `json:"chrome_peak_rss_bytes,omitempty"`
+	ChromeCPUSeconds   float64 `json:"chrome_cpu_seconds,omitempty"`
+}
+
+// Clock ticks per second used by /proc/<pid>/stat on Linux
+const procClockTicks = 100
+
+// Measure the scraper and, while it still runs, the Chrome process tree
+func collectResources(ctx context.Context) *resourceUsage {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	usage := &resourceUsage{GoSysBytes: mem.Sys}
+	usage.ProcessMaxRSSBytes, usage.ProcessCPUSeconds = selfUsage()
+
+	if c := chromedp.FromContext(ctx); c != nil && c.Browser != nil {
+		if proc := c.Browser.Process(); proc != nil {
+			// Renderers and helpers are children of the browser process
+			for _, pid := range processTree(proc.Pid) {
+				rss, cpu, ok := procUsage(pid)
+				if !ok {
+					continue
+				}
+				usage.ChromeProcesses++
+				usage.ChromePeakRSSBytes += rss
+				usage.ChromeCPUSeconds += cpu
+			}
+		}
+	}
+	return usage
+}
+
+// root and all of its descendants, read from /proc
+func processTree(root int) []int {
+	children := make(map[int][]int)
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// The command name may contain spaces, fields start after ")"
+		fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		pid, _ := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+		ppid, _ := strconv.Atoi(fields[1])
+		children[ppid] = append(children[ppid], pid)
+	}
+
+	tree := []int{root}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree
+}
+
+// Peak RSS and CPU seconds of one process
+func procUsage(pid int) (int64, float64, bool) {
+	base := filepath.Join("/proc", strconv.Itoa(pid))
+	status, err := os.ReadFile(filepath.Join(base, "status"))
+	if err != nil {
+		return 0, 0, false
+	}
+	var rss int64
+	for _, line := range strings.Split(string(status), "\n") {
+		if value, ok := strings.CutPrefix(line, "VmHWM:"); ok {
+			kb, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			rss = kb * 1024
+		}
+	}
+
+	var cpu float64
+	if stat, err := os.ReadFile(filepath.Join(base, "stat")); err == nil {
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		// utime and stime are fields 14 and 15 of the full line
+		if len(fields) > 12 {
+			utime, _ := strconv.ParseFloat(fields[11], 64)
+			stime, _ := strconv.ParseFloat(fields[12], 64)
+			cpu = (utime + stime) / procClockTicks
+		}
+	}
+	return rss, cpu, true
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package scraper
+
+// Not measured outside of unix systems
+func selfUsage() (int64, float64) {
+	return 0, 0
+}
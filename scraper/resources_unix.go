@@ -0,0 +1,23 @@
+//go:build unix
+
+package scraper
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// Peak RSS and CPU seconds of the scraper process itself
+func selfUsage() (int64, float64) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, 0
+	}
+	maxRSS := int64(ru.Maxrss)
+	// Linux reports kilobytes, the BSDs and macOS bytes
+	if runtime.GOOS == "linux" {
+		maxRSS *= 1024
+	}
+	cpu := float64(ru.Utime.Sec+ru.Stime.Sec) + float64(ru.Utime.Usec+ru.Stime.Usec)/1e6
+	return maxRSS, cpu
+}
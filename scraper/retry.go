@@ -0,0 +1,39 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// First wait between attempts, doubled after every retry
+const retryBaseDelay = time.Second
+
+// Server errors, timeouts and network failures may pass on a second try;
+// 4xx answers and certificate problems won't.
+func isTransientFailure(status int64, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return true
+		}
+		msg := err.Error()
+		return strings.Contains(msg, "net::ERR_") &&
+			!strings.Contains(msg, "net::ERR_CERT_") && !strings.Contains(msg, "net::ERR_SSL_")
+	}
+	return status >= 500
+}
+
+// Short description of a failed attempt for the retry log line
+func failureReason(status int64, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("status %d", status)
+}
+
+// 1s, 2s, 4s, ...
+func retryBackoff(attempt int) time.Duration {
+	return retryBaseDelay << (attempt - 1)
+}
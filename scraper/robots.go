@@ -0,0 +1,181 @@
+package scraper
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// One Allow/Disallow line of a robots.txt group
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// Parsed robots.txt per scheme, host and user agent, fetched once for the
+// whole run however many pages of the host it scrapes
+type robotsCache struct {
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// Rules of one robots.txt; once lets parallel pages of a host wait for the
+// first fetch instead of each sending their own
+type robotsEntry struct {
+	once  sync.Once
+	rules []robotsRule
+	err   error
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{hosts: make(map[string]*robotsEntry)}
+}
+
+// checkRobots reports whether userAgent may fetch rawURL, fetching
+// /robots.txt of its host the first time. A missing file (4xx) allows
+// everything; a failed fetch is remembered too and returned for every page
+// of the host.
+func (c *robotsCache) checkRobots(client *http.Client, rawURL, userAgent string) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+	if !isHTTPURL(u) {
+		return true, nil
+	}
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: strings.ToLower(u.Host), Path: "/robots.txt"}
+
+	key := robotsURL.String() + " " + userAgent
+	c.mu.Lock()
+	entry := c.hosts[key]
+	if entry == nil {
+		entry = &robotsEntry{}
+		c.hosts[key] = entry
+	}
+	c.mu.Unlock()
+	entry.once.Do(func() {
+		entry.rules, entry.err = fetchRobots(client, robotsURL.String(), userAgent)
+	})
+	if entry.err != nil {
+		return false, entry.err
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return robotsAllowed(entry.rules, path), nil
+}
+
+// Rules for userAgent in the robots.txt at robotsURL, none when it is missing
+func fetchRobots(client *http.Client, robotsURL, userAgent string) ([]robotsRule, error) {
+	req, err := http.NewRequest(http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching robots.txt: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("robots.txt answered %d", resp.StatusCode)
+	}
+	return parseRobots(io.LimitReader(resp.Body, 512<<10), userAgent), nil
+}
+
+// Rules of the group that names userAgent, or of the * group when none
+// does. User agent tokens match case-insensitively as substrings, so
+// "chrome" applies to a Chrome browser string.
+func parseRobots(r io.Reader, userAgent string) []robotsRule {
+	userAgent = strings.ToLower(userAgent)
+	var specific, wildcard []robotsRule
+	var agents []string
+	inRules, named := false, false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agent := strings.ToLower(value)
+			agents = append(agents, agent)
+			if agent != "*" && agent != "" && strings.Contains(userAgent, agent) {
+				named = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			for _, agent := range agents {
+				if agent == "*" {
+					wildcard = append(wildcard, rule)
+				} else if agent != "" && strings.Contains(userAgent, agent) {
+					specific = append(specific, rule)
+				}
+			}
+		}
+	}
+	if named {
+		return specific
+	}
+	return wildcard
+}
+
+// Longest matching pattern wins, Allow wins ties
+func robotsAllowed(rules []robotsRule, path string) bool {
+	allowed, best := true, -1
+	for _, rule := range rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			allowed, best = rule.allow, n
+		}
+	}
+	return allowed
+}
+
+// Prefix match with * wildcards and a trailing $ anchor
+func robotsMatch(pattern, path string) bool {
+	if !strings.ContainsAny(pattern, "*$") {
+		return strings.HasPrefix(path, pattern)
+	}
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	re, err := regexp.Compile(expr)
+	return err == nil && re.MatchString(path)
+}
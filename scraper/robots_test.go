@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const robotsFixture = `# comment
+User-agent: *
+Disallow: /private
+Allow: /private/open
+Disallow: /*.pdf$
+
+User-agent: ScrapperBot
+User-agent: otherbot
+Disallow: /
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name, agent, path string
+		want              bool
+	}{
+		{"wildcard allows by default", "Mozilla/5.0", "/public", true},
+		{"wildcard disallow", "Mozilla/5.0", "/private/page", false},
+		{"longer allow wins", "Mozilla/5.0", "/private/open/page", true},
+		{"anchored wildcard", "Mozilla/5.0", "/docs/file.pdf", false},
+		{"anchor stops at the end", "Mozilla/5.0", "/docs/file.pdf?page=2", true},
+		{"named group replaces wildcard", "Mozilla/5.0 scrapperbot/1.0", "/public", false},
+		{"second agent of a group", "otherbot", "/public", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(robotsFixture), tt.agent)
+			if got := robotsAllowed(rules, tt.path); got != tt.want {
+				t.Errorf("robotsAllowed(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []robotsRule
+		path  string
+		want  bool
+	}{
+		{"no rules", nil, "/a", true},
+		{"prefix", []robotsRule{{pattern: "/a"}}, "/abc", false},
+		{"no match", []robotsRule{{pattern: "/a"}}, "/b", true},
+		{"allow wins ties", []robotsRule{{pattern: "/a"}, {allow: true, pattern: "/a"}}, "/a", true},
+		{"longest wins", []robotsRule{{allow: true, pattern: "/a"}, {pattern: "/a/b"}}, "/a/b/c", false},
+		{"star in the middle", []robotsRule{{pattern: "/*/edit"}}, "/page/edit", false},
+		{"dollar anchor", []robotsRule{{pattern: "/a$"}}, "/a/b", true},
+		{"pattern is literal", []robotsRule{{pattern: "/a.b*"}}, "/axb", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := robotsAllowed(tt.rules, tt.path); got != tt.want {
+				t.Errorf("robotsAllowed(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRobots(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.URL.Path != "/robots.txt" {
+			t.Errorf("requested %s, want /robots.txt", r.URL.Path)
+		}
+		if r.Header.Get("User-Agent") != "scrapperbot" {
+			t.Errorf("User-Agent = %q, want scrapperbot", r.Header.Get("User-Agent"))
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer srv.Close()
+
+	robots := newRobotsCache()
+	for path, want := range map[string]bool{"/public": true, "/private?x=1": false, "/private/page": false, "/": true} {
+		allowed, err := robots.checkRobots(srv.Client(), srv.URL+path, "scrapperbot")
+		if err != nil {
+			t.Fatalf("checkRobots: %v", err)
+		}
+		if allowed != want {
+			t.Errorf("checkRobots(%s) = %v, want %v", path, allowed, want)
+		}
+	}
+	// Once per host, not once per page
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", n)
+	}
+}
+
+func TestCheckRobotsFetchesOncePerHostInParallel(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer srv.Close()
+
+	robots := newRobotsCache()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := robots.checkRobots(srv.Client(), fmt.Sprintf("%s/page/%d", srv.URL, i), "scrapperbot"); err != nil {
+				t.Errorf("checkRobots: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", n)
+	}
+}
+
+func TestCheckRobotsMissingFile(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	allowed, err := newRobotsCache().checkRobots(srv.Client(), srv.URL+"/anything", "scrapperbot")
+	if err != nil {
+		t.Fatalf("checkRobots: %v", err)
+	}
+	if !allowed {
+		t.Error("a missing robots.txt should allow everything")
+	}
+}
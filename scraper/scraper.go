@@ -0,0 +1,1811 @@
+// Package scraper loads pages in headless Chrome and saves their HTML,
+// screenshot, links and whatever else the Options ask for into a run folder.
+package scraper
+
+import (
+	"context"
+	"encoding/json" // for unmarshal problems
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os" // for reading scripts and stdout
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings" // String operations is able to record link hrefs
+	"sync"
+	"text/template"
+	"time" // need to set timeout
+
+	// for network conditions and http code
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+)
+
+// Scraper scrapes pages with one set of Options. The files it needs
+// (tag rules, scripts, cookies, template, database) are loaded once by
+// New and shared by every page.
+type Scraper struct {
+	o   *Options
+	env *runEnv
+}
+
+// New loads everything o points at, so broken files show up before any
+// browser starts. Close the Scraper when done with it.
+func New(o Options) (*Scraper, error) {
+	// Zero values that would make every page fail get the CLI defaults
+	if o.Timeout <= 0 {
+		o.Timeout = 120 * time.Second
+	}
+	if o.Width <= 0 || o.Height <= 0 {
+		o.Width, o.Height = 1920, 1080
+	}
+	// The document takes the place of the run folder
+	if o.JSONOutput {
+		o.NoFiles = true
+	}
+	if o.LogOutput == nil {
+		o.LogOutput = os.Stderr
+	}
+	if o.DiffMode == "" {
+		o.DiffMode = "text"
+	}
+	if o.DownloadConcurrency <= 0 {
+		o.DownloadConcurrency = imageWorkers
+	}
+	env := &runEnv{
+		log:         newLogger(o.LogOutput, o.LogLevel),
+		screenshots: newSemaphore(o.MaxConcurrentScreenshots),
+		downloads:   newSemaphore(o.DownloadConcurrency),
+		client:      newStaticClient(o.Proxy, o.ProxyUser, o.ProxyPass, o.DownloadConcurrency),
+		robots:      newRobotsCache(),
+		jsonWriter:  o.JSONWriter,
+	}
+	if env.jsonWriter == nil {
+		env.jsonWriter = os.Stdout
+	}
+	if !o.IsolateCookies {
+		env.jar = newCookieJar()
+	}
+	// Too many tabs fail mid-crawl with "too many open files"
+	if n, limit := fdSafeConcurrency(o.Concurrency, o.MaxFileDescriptors); n < o.Concurrency {
+		env.log.Printf("-concurrency %d needs about %d file descriptors, the limit is %d: scraping %d pages at once "+
+			"(raise it with ulimit -n, or -max-file-descriptors -1 to skip this check)\n",
+			o.Concurrency, o.Concurrency*FileDescriptorsPerPage+fdReserve, limit, n)
+		o.Concurrency = n
+	}
+
+	// Broken rule files should stop us before the browser starts
+	if o.TagRules != "" {
+		rules, err := loadTagRules(o.TagRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tag rules: %v", err)
+		}
+		env.tagRules = rules
+	}
+
+	if o.EvalAfter != "" {
+		data, err := os.ReadFile(o.EvalAfter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read eval-after script: %v", err)
+		}
+		env.evalScript = string(data)
+	}
+
+	if o.Cookies != "" {
+		cookies, err := loadCookies(o.Cookies)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cookies: %v", err)
+		}
+		env.cookies = cookies
+	}
+
+	if o.Template != "" {
+		if _, err := templateFileName(o.TemplateExt); err != nil {
+			return nil, err
+		}
+		tmpl, err := loadOutputTemplate(o.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load template: %v", err)
+		}
+		env.outputTemplate = tmpl
+	}
+
+	if o.Resume != "" {
+		st, err := loadCrawlState(o.Resume)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load crawl state: %v", err)
+		}
+		env.resume = st
+	}
+
+	if o.NameTemplate != "" {
+		tmpl, err := parseNameTemplate(o.NameTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name template: %v", err)
+		}
+		env.nameTemplate = tmpl
+	}
+
+	// Schema problems should also show up before any scraping
+	if o.SQLite != "" {
+		store, err := openPageStore(o.SQLite)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database: %v", err)
+		}
+		env.db = store
+	}
+	return &Scraper{o: &o, env: env}, nil
+}
+
+// Close releases the database opened for Options.SQLite
+func (s *Scraper) Close() error {
+	if s.env.db != nil {
+		return s.env.db.Close()
+	}
+	return nil
+}
+
+// Scrape loads one URL and saves it into its own run folder. The result is
+// returned whenever the run got far enough to have a manifest, even
+// together with an error.
+func (s *Scraper) Scrape(ctx context.Context, rawURL string) (*Result, error) {
+	return scrapePage(ctx, s.o, s.env, nil, rawURL)
+}
+
+// Crawl scrapes the start URLs, following same-host links up to
+// Options.Depth, and reports one summary per page in the order they ran
+func (s *Scraper) Crawl(ctx context.Context, starts []string) []PageSummary {
+	return crawl(ctx, s.o, s.env, starts)
+}
+
+// ErrNavigation is wrapped around errors of the page load itself
+var ErrNavigation = errors.New("failed to navigate")
+
+// Loaded once and shared by every URL of the batch
+type runEnv struct {
+	log *logger
+	// Screenshots are memory heavy, so they get their own limit on top of
+	// however many pages are open
+	screenshots semaphore
+	// Image and asset downloads, one limit however many pages are open
+	downloads semaphore
+	// Plain HTTP requests: -static-links, robots.txt, images, link checks
+	client *http.Client
+	robots *robotsCache
+
+	tagRules       map[string]tagRule
+	evalScript     string
+	cookies        []*network.CookieParam
+	jar            *cookieJar
+	outputTemplate *template.Template
+	nameTemplate   *template.Template
+	resume         *crawlState
+	db             *pageStore
+	// One JSON document at a time, whatever the concurrency
+	jsonMu     sync.Mutex
+	jsonWriter io.Writer
+}
+
+// Serializes picking and creating run folders
+var runFolderMu sync.Mutex
+
+// Outcome of one page of a crawl, one line of the end of batch summary
+type PageSummary struct {
+	URL        string
+	Err        error
+	StatusCode int64
+	LinksCount int
+	Internal   int
+	External   int
+	Skipped    string
+	// The text differs from the Options.Compare run
+	Changed bool
+	// "captcha" for challenge pages
+	Blocked string
+	// A cookie banner was shown and not accepted
+	ConsentBanner bool
+	// Transferred by the browser for the page and its resources
+	BytesDownloaded int64
+}
+
+func newPageSummary(rawURL string, r *Result, err error) PageSummary {
+	s := PageSummary{URL: rawURL, Err: err}
+	if r != nil {
+		m := r.Manifest
+		s.StatusCode = m.StatusCode
+		s.LinksCount = m.LinksCount
+		s.Internal = m.InternalLinks
+		s.External = m.ExternalLinks
+		s.Skipped = m.Skipped
+		s.Changed = m.Changed
+		s.Blocked = m.Blocked
+		s.ConsentBanner = m.Consent != nil && !m.Consent.Accepted
+		s.BytesDownloaded = m.BytesDownloaded
+	}
+	return s
+}
+
+func (s PageSummary) String() string {
+	if s.Err != nil {
+		return fmt.Sprintf("FAIL %s: %v", s.URL, s.Err)
+	}
+	if s.Skipped != "" {
+		return fmt.Sprintf("SKIP %s (%s)", s.URL, s.Skipped)
+	}
+	notes := ""
+	if s.Changed {
+		notes += ", changed"
+	}
+	if s.Blocked != "" {
+		notes += ", blocked by " + s.Blocked
+	}
+	if s.ConsentBanner {
+		notes += ", cookie banner"
+	}
+	return fmt.Sprintf("OK   %s (status %d, %d links: %d internal, %d external%s)",
+		s.URL, s.StatusCode, s.LinksCount, s.Internal, s.External, notes)
+}
+
+// Scrape one URL into its own run folder. Errors are returned instead of
+// exiting so the caller can go on with the next URL; the result is
+// returned whenever the run got far enough to have a manifest.
+func scrapePage(parent context.Context, o *Options, env *runEnv, shared *sharedBrowser, rawURL string) (*Result, error) {
+	result := &Result{}
+	lg := env.log
+	rawURL, err := normalizeInputURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	lg.infof("Navigating to URL: %s\n", rawURL)
+
+	// Create files
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %v", err)
+	}
+	hostname := parsedURL.Hostname()
+
+	// The time to be added for files name
+	startTime := time.Now()
+	timestamp := startTime.Format("2006-01-02_15-04-05")
+
+	// Every log line of this run carries its ID
+	runID := newRunID(rawURL, startTime)
+	lg = lg.withRunID(runID)
+
+	baseDir := o.Out
+	out := &outputDir{
+		path:     runFolderPath(baseDir, hostname, timestamp, o.GroupByHost),
+		disabled: o.NoFiles || o.CheckOnly,
+
+		maxFileSize: o.MaxFileSize,
+		dirPerm:     o.dirMode(),
+		filePerm:    o.fileMode(),
+	}
+	// Checked and created in one step so parallel pages of a host can't
+	// pick the same folder
+	runFolderMu.Lock()
+	if o.Flat {
+		// Predictable paths, a later run overwrites the files
+		out.path = baseDir
+	} else if o.NoTimestampFolder {
+		// One stable folder per host, overwritten by every run
+		out.path = filepath.Join(baseDir, sanitizeHost(hostname))
+	} else if env.nameTemplate != nil {
+		name, err := renderFolderName(env.nameTemplate, folderNameData{Host: hostname, Timestamp: timestamp, URLHash: urlHash(rawURL)})
+		if err != nil {
+			runFolderMu.Unlock()
+			return nil, fmt.Errorf("failed to render name template: %v", err)
+		}
+		out.path = filepath.Join(baseDir, name)
+		if o.RunIDInName || out.exists() {
+			out.path += "_" + runID
+		}
+	} else if o.RunIDInName || out.exists() {
+		// The same host twice within a second would share a folder
+		out.path += "_" + runID
+	}
+	if !insideDir(baseDir, out.path) {
+		runFolderMu.Unlock()
+		return nil, fmt.Errorf("run folder %s escapes the output directory %s", out.path, baseDir)
+	}
+	// Read before anything is written, the run to compare with may be the
+	// folder this run is about to overwrite
+	var previous previousRun
+	if o.Compare != "" {
+		previous = loadPreviousRun(o.Compare, o.DiffMode)
+	}
+	err = out.create()
+	runFolderMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	if !out.disabled {
+		lg.infof("The Registry folder is created: %s", out.path)
+		result.Dir = out.path
+	}
+
+	manifest := &RunManifest{
+		RunID:     runID,
+		URL:       rawURL,
+		Timestamp: startTime.Format(time.RFC3339),
+
+		ChromedpVersion: chromedpVersion(),
+	}
+	result.Manifest = manifest
+
+	// Console output of the page, when -capture-console is set
+	var console *consoleLog
+	saveConsole := func() {
+		if console == nil {
+			return
+		}
+		if savepath, err := out.writeFile("console.log", []byte(console.text())); err != nil {
+			lg.Printf("Failed to save console output: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("%d console messages saved to %s\n", console.count(), savepath)
+		}
+	}
+
+	// Hosts whose responses were loaded despite a certificate error
+	relaxed := newHostSet()
+
+	// Written at the end of the run, or early for pages that get skipped
+	finishRun := func() {
+		saveConsole()
+		if hosts := relaxed.list(); len(hosts) > 0 {
+			manifest.RelaxedTLSHosts = hosts
+		}
+		result.URL = rawURL
+		result.StatusCode = manifest.StatusCode
+		manifest.ElapsedMS = time.Since(startTime).Milliseconds()
+		manifest.Interrupted = parent.Err() != nil
+		manifest.Files = out.written()
+		if env.outputTemplate != nil {
+			if savepath, err := writeTemplateOutput(out, env.outputTemplate, o.TemplateExt, result); err != nil {
+				lg.Printf("Failed to render template: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("Template output saved to %s\n", savepath)
+			}
+		}
+
+		if o.JSONOutput {
+			env.jsonMu.Lock()
+			err := writeJSONResult(env.jsonWriter, result, o.JSONScreenshot, o.JSONText)
+			env.jsonMu.Unlock()
+			if err != nil {
+				lg.Printf("Failed to write JSON output: %v\n", err)
+			}
+			return
+		}
+
+		var savepath string
+		var err error
+		if o.CompactManifest {
+			savepath, err = writeCompactManifest(out, baseDir, manifest)
+		} else {
+			savepath, err = writeManifest(out, manifest)
+		}
+		if err != nil {
+			lg.Printf("Failed to save manifest: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Manifest saved to %s\n", savepath)
+		}
+		// The folder now holds this run's files, the rest is left over
+		if o.NoTimestampFolder {
+			out.removeStale(lg, startTime)
+		}
+	}
+
+	// links.txt plus the on-host / off-host split, for either fetch mode
+	saveLinks := func(found []pageLink) {
+		found, pseudo := splitPseudoLinks(found)
+		if o.PseudoLinks {
+			if savepath, err := out.writeFile("pseudo_links.txt", []byte(strings.Join(linkURLs(pseudo), "\n"))); err != nil {
+				lg.Printf("Failed to save pseudo links: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("%d javascript:, mailto: and tel: links saved to %s\n", len(pseudo), savepath)
+			}
+		}
+		links := linkURLs(found)
+		manifest.LinksCount = len(links)
+		result.Links = links
+		data, err := formatLinks(found, o.LinksFormat, hostname, o.IncludeSubdomains)
+		if err != nil {
+			lg.Printf("Failed to format links: %v\n", err)
+		} else if savepath, err := out.writeFile("links."+o.LinksFormat, data); err != nil {
+			lg.Printf("Failed to save links: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Links saved to %d links in %s\n", len(links), savepath)
+		}
+
+		internal, external := splitLinks(links, hostname, o.IncludeSubdomains)
+		manifest.InternalLinks = len(internal)
+		manifest.ExternalLinks = len(external)
+		if _, err := out.writeFile("links_internal.txt", []byte(strings.Join(internal, "\n"))); err != nil {
+			lg.Printf("Failed to save internal links: %v\n", err)
+		}
+		if _, err := out.writeFile("links_external.txt", []byte(strings.Join(external, "\n"))); err != nil {
+			lg.Printf("Failed to save external links: %v\n", err)
+		}
+		lg.infof("%d internal and %d external links\n", len(internal), len(external))
+	}
+
+	// Robot-like behaviour is blocked by some websites
+	userAgent := userAgents[0]
+	if o.Device != nil {
+		userAgent = o.Device.UserAgent
+	}
+	if o.UserAgent != "" {
+		userAgent = o.UserAgent
+	}
+
+	// Polite by default: disallowed pages are recorded but not loaded
+	if !o.IgnoreRobots {
+		allowed, err := env.robots.checkRobots(env.client, rawURL, userAgent)
+		if err != nil {
+			lg.Printf("Failed to check robots.txt, scraping anyway: %v\n", err)
+		} else if !allowed {
+			lg.infof("robots.txt disallows this URL, skipping it (use -ignore-robots to scrape anyway)")
+			manifest.Skipped = "robots"
+			finishRun()
+			return result, nil
+		}
+	}
+
+	// Static pages don't need Chrome just for their links
+	if o.StaticLinks && !o.CheckOnly && isHTTPURL(parsedURL) {
+		page, err := fetchStaticLinks(env.client, rawURL, userAgent, o.Headers, o.BasicAuthUser, o.BasicAuthPass)
+		switch {
+		case err != nil:
+			lg.Printf("Static fetch failed, using the browser: %v\n", err)
+		case page.NeedsJS:
+			lg.Printf("Page looks JavaScript rendered (%d links), using the browser\n", len(page.Links))
+		default:
+			listNetworkRequests(lg, int64(page.Status), "")
+			result.Metadata = page.Metadata
+			if savepath, err := out.writeJSON("metadata.json", page.Metadata); err != nil {
+				lg.Printf("Failed to save metadata: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("Metadata saved to %s\n", savepath)
+			}
+			saveLinks(normalizeLinks(nil, page.Links, o.KeepFragments, o.StripTrailingSlash))
+			manifest.StatusCode = int64(page.Status)
+			manifest.UserAgent = userAgent
+			manifest.Mode = "static"
+			finishRun()
+			return result, nil
+		}
+	}
+
+	// Custom options for allocator
+	opts := allocatorOptions(o)
+
+	lg.infof("Targeting URL: %s\n", rawURL)
+
+	// Enable network events to capture status codes
+	var statusCode int64
+	var statusText string
+
+	// Tabs opened by the page, when -follow-new-targets is set
+	var newTargets *targetCollector
+	// Per request durations, when -slow-requests is set
+	var timings *requestTimer
+	// Every response, for network.json
+	var responses *responseLog
+	// HTTP redirects of the page itself
+	var redirects *redirectWatcher
+	// In-flight requests, when -wait-idle is set
+	var idle *networkIdle
+
+	// Best-effort error.png of whatever the page shows
+	saveErrorScreenshot := func(ctx context.Context) {
+		if !o.ScreenshotOnError {
+			return
+		}
+		imgData, err := captureErrorScreenshot(ctx)
+		if err != nil {
+			// Never hide the original failure behind this one
+			lg.Printf("Failed to capture error screenshot: %v\n", err)
+		} else if savepath, err := out.writeFile("error.png", imgData); err != nil {
+			lg.Printf("Failed to save error screenshot: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Error screenshot saved to %s\n", savepath)
+		}
+	}
+
+	// Called when navigation or a wait failed
+	onError := func(ctx context.Context) {
+		if !o.ScreenshotOnError {
+			return
+		}
+		// A challenge page is the usual reason a load never settles
+		if report, err := detectCaptcha(ctx); err == nil && report.Detected {
+			lg.Printf("Page is blocked by a captcha (%s)\n", report.Provider)
+		}
+		saveErrorScreenshot(ctx)
+	}
+
+	// Start a browser, load the page and wait until it is ready for capture
+	// On error the browser is already closed
+	openPage := func(headless bool) (context.Context, context.CancelFunc, error) {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		// A tab of the crawl's browser, unless the page needs a browser
+		// set up differently: a visible one for the fallback, or one with
+		// certificate checks off for an allowlisted host, which is
+		// browser wide
+		if shared != nil && headless == !o.Headful && !(o.strictTLS() && hostAllowed(hostname, o.InsecureHosts)) {
+			lg.debugf("Opening a tab with user agent %s", userAgent)
+			var err error
+			if ctx, cancel, err = shared.newTab(o.Timeout); err != nil {
+				return nil, nil, fmt.Errorf("%w: %w", ErrNavigation, err)
+			}
+			if err := chromedp.Run(ctx, emulation.SetUserAgentOverride(userAgent)); err != nil {
+				cancel()
+				return nil, nil, fmt.Errorf("failed to set the user agent: %v", err)
+			}
+		} else {
+			lg.debugf("Starting browser (headless %t) with user agent %s", headless, userAgent)
+			ctx, cancel = newBrowser(parent, lg, append(opts[:len(opts):len(opts)], chromedp.UserAgent(userAgent)), headless, o.Timeout)
+		}
+		statusCode, statusText = 0, ""
+		// Written by the listener goroutine, read once the navigation is done
+		document := &documentStatus{}
+		responses = watchResponses(ctx)
+		redirects = watchRedirects(ctx)
+		if o.WaitIdle {
+			idle = watchNetworkIdle(ctx, o.WaitIdleRequests)
+		}
+		if o.CaptureConsole {
+			console = watchConsole(ctx)
+		}
+		if o.FollowNewTargets {
+			newTargets = watchNewTargets(ctx)
+		}
+		if o.SlowRequests > 0 {
+			timings = watchRequestTimings(ctx, hostname)
+		}
+
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			if ev, ok := ev.(*network.EventResponseReceived); ok {
+				// Just capture the main document response
+				if ev.Type == network.ResourceTypeDocument {
+					insecure := ""
+					if o.strictTLS() && isRelaxedResponse(ev.Response) {
+						if u, err := url.Parse(ev.Response.URL); err == nil && !o.relaxedTLSAllowed(u.Hostname()) {
+							insecure = u.Hostname()
+						}
+					}
+					document.set(ev.Response.Status, ev.Response.StatusText, insecure)
+				}
+				if o.strictTLS() && isRelaxedResponse(ev.Response) {
+					if u, err := url.Parse(ev.Response.URL); err == nil {
+						relaxed.add(u.Hostname())
+					}
+				}
+			}
+		})
+
+		// The override is browser wide, so it is switched for the page host
+		// before the navigation starts. Subresources of other hosts get
+		// through too, only the landing page is checked again below.
+		if o.strictTLS() {
+			ignore := hostAllowed(hostname, o.InsecureHosts)
+			if err := chromedp.Run(ctx, security.SetIgnoreCertificateErrors(ignore)); err != nil {
+				cancel()
+				return nil, nil, fmt.Errorf("failed to configure TLS checks: %v", err)
+			}
+		}
+
+		if o.ProxyUser != "" || o.BasicAuthUser != "" {
+			auth := authConfig{
+				proxyUser: o.ProxyUser, proxyPass: o.ProxyPass,
+				host: hostname, user: o.BasicAuthUser, pass: o.BasicAuthPass,
+			}
+			if err := enableAuth(ctx, lg, auth); err != nil {
+				cancel()
+				return nil, nil, fmt.Errorf("failed to set up authentication: %v", err)
+			}
+		}
+
+		// A service worker can answer from a stale cache or an app shell
+		if o.BypassServiceWorker {
+			if err := chromedp.Run(ctx, network.SetBypassServiceWorker(true)); err != nil {
+				cancel()
+				return nil, nil, fmt.Errorf("failed to bypass service workers: %v", err)
+			}
+		}
+
+		if o.Device != nil {
+			if err := emulateDevice(ctx, *o.Device); err != nil {
+				cancel()
+				return nil, nil, err
+			}
+		}
+
+		// API keys and auth headers go out from the first request on
+		if len(o.Headers) > 0 {
+			if err := setExtraHeaders(ctx, o.Headers); err != nil {
+				cancel()
+				return nil, nil, err
+			}
+		}
+
+		// Logged-in sessions: cookies have to be in place before the first request
+		if len(env.cookies) > 0 {
+			if err := setCookies(ctx, env.cookies); err != nil {
+				cancel()
+				return nil, nil, err
+			}
+		}
+		// What earlier pages of the host set goes on top, so fresher values
+		// win. Losing them only costs the session, not the page.
+		if jarred := env.jar.get(hostname); len(jarred) > 0 {
+			if err := setCookies(ctx, jarred); err != nil {
+				lg.Printf("Failed to carry over cookies of earlier pages: %v\n", err)
+			}
+		}
+
+		// Navigate to the URL, the response listener records the status
+		err := chromedp.Run(ctx, chromedp.Navigate(rawURL))
+		var insecureDocument string
+		statusCode, statusText, insecureDocument = document.get()
+		// print network request status
+		listNetworkRequests(lg, statusCode, statusText)
+		if err != nil {
+			onError(ctx)
+			cancel()
+			if reason := navigationFailure(hostname, err); reason != "" {
+				return nil, nil, fmt.Errorf("%w: %s: %w", ErrNavigation, reason, err)
+			}
+			return nil, nil, fmt.Errorf("%w: %w", ErrNavigation, err)
+		}
+		// Redirected off the allowlisted host onto a broken certificate
+		if insecureDocument != "" {
+			cancel()
+			return nil, nil, fmt.Errorf("%w: certificate error on %s, which is not in -insecure-hosts", ErrNavigation, insecureDocument)
+		}
+
+		// Site specific preparation, errors are only logged
+		if env.evalScript != "" {
+			if err := runEvalHook(ctx, env.evalScript, o.EvalAfterWait); err != nil {
+				lg.Println(err)
+			}
+		}
+
+		// Enter in a search box, Escape on a modal, ...
+		if len(o.KeyInputs) > 0 {
+			if err := sendKeyInputs(ctx, o.KeyInputs); err != nil {
+				lg.Println(err)
+			}
+		}
+
+		// Reveal hover menus last so typing doesn't move them away
+		if len(o.Hover) > 0 {
+			hoverElements(ctx, lg, o.Hover)
+		}
+
+		// Late XHRs after the load event: capture anyway when it never settles
+		if idle != nil {
+			if err := idle.wait(ctx, o.WaitIdleQuiet, o.WaitIdleTimeout); err != nil {
+				lg.Printf("Wait for network idle timed out, capturing anyway: %v\n", err)
+				onError(ctx)
+			}
+		}
+
+		// Spinner style readiness: capture anyway when it never disappears
+		if o.WaitGone != "" {
+			if err := waitGone(ctx, o.WaitGone, o.WaitGoneTimeout); err != nil {
+				lg.Printf("Wait for selector to disappear timed out, capturing anyway: %v\n", err)
+				onError(ctx)
+			}
+		}
+
+		// SPA content: an empty shell is not worth capturing
+		if o.WaitFor != "" {
+			if err := waitVisible(ctx, o.WaitFor, o.WaitForTimeout); err != nil {
+				onError(ctx)
+				cancel()
+				return nil, nil, err
+			}
+		}
+
+		// Incrementally loaded lists: capture whatever arrived on timeout
+		if o.WaitCountSelector != "" {
+			if err := waitCount(ctx, o.WaitCountSelector, o.WaitCountN, o.WaitCountTimeout); err != nil {
+				lg.Printf("Wait for element count timed out, capturing anyway: %v\n", err)
+				onError(ctx)
+			}
+		}
+		return ctx, cancel, nil
+	}
+
+	// Transient failures start over in a fresh tab with a fresh deadline
+	ctx, cancel, err := openPage(!o.Headful)
+	for attempt := 1; attempt <= o.Retries && isTransientFailure(statusCode, err); attempt++ {
+		wait := retryBackoff(attempt)
+		lg.Printf("Attempt %d of %d failed (%s), retrying in %s\n", attempt, o.Retries+1, failureReason(statusCode, err), wait)
+		if err == nil {
+			cancel()
+		}
+		select {
+		case <-parent.Done():
+		case <-time.After(wait):
+		}
+		ctx, cancel, err = openPage(!o.Headful)
+	}
+	if err != nil {
+		// Interrupted runs still leave a manifest of how far they got
+		if parent.Err() != nil {
+			finishRun()
+		} else {
+			// Script errors are often why the page never got ready
+			saveConsole()
+		}
+		return result, err
+	}
+	defer func() {
+		// Later pages of the host continue this page's session; a tab that
+		// was closed for a reload that then failed has none left
+		if env.jar != nil && ctx != nil && ctx.Err() == nil {
+			if err := env.jar.save(ctx, hostname); err != nil {
+				lg.debugf("Failed to keep cookies for %s: %v", hostname, err)
+			}
+		}
+		cancel()
+	}()
+
+	// UA based blocks: start over with the next browser identity
+	if o.RetryDifferentUA {
+		for next := 1; isBlockingStatus(statusCode) && next < len(userAgents); next++ {
+			lg.Printf("Request blocked (%d), retrying with another user agent\n", statusCode)
+			userAgent = userAgents[next]
+			cancel()
+			// The deferred cleanup keeps the old, already cancelled tab
+			// unless the new one opens
+			nextCtx, nextCancel, err := openPage(!o.Headful)
+			if err != nil {
+				return result, err
+			}
+			ctx, cancel = nextCtx, nextCancel
+		}
+	}
+
+	// Anti-headless sites tend to serve an empty body, so try once with a
+	// visible browser before capturing
+	headfulFallback := false
+	if o.AutoHeadfulFallback && !o.Headful {
+		if n, err := visibleTextLength(ctx); err != nil {
+			lg.Printf("Failed to measure page text: %v\n", err)
+		} else if n < blankTextThreshold {
+			lg.Printf("Page text is nearly empty (%d chars) in headless mode, retrying with a visible browser\n", n)
+			cancel()
+			nextCtx, nextCancel, err := openPage(false)
+			if err != nil {
+				return result, err
+			}
+			ctx, cancel = nextCtx, nextCancel
+			headfulFallback = true
+		}
+	}
+
+	manifest.StatusCode = statusCode
+	manifest.UserAgent = userAgent
+	if chain := redirects.chain(statusCode); chain != nil {
+		manifest.Redirects = chain
+		manifest.FinalURL = chain[len(chain)-1].URL
+		manifest.HostChanged = redirectChangedHost(rawURL, manifest.FinalURL)
+		lg.infof("Redirects: %s\n", redirectSummary(chain))
+		if manifest.HostChanged {
+			lg.Printf("Final URL %s is on another host than %s, possibly a parking page or hijack\n", manifest.FinalURL, rawURL)
+		}
+	}
+	if err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, product, _, _, _, err := browser.GetVersion().Do(ctx)
+		manifest.BrowserVersion = product
+		return err
+	})); err != nil {
+		lg.Printf("Failed to read browser version: %v\n", err)
+	}
+	manifest.HeadfulFallback = headfulFallback
+
+	// Reachability sweeps stop at the status, nothing is captured
+	if o.CheckOnly {
+		lg.infof("Status: %d %s\n", statusCode, statusText)
+		finishRun()
+		return result, nil
+	}
+
+	// Consent overlays hide the content, so they're noted before capture
+	// and clicked away with -auto-accept-cookies
+	if consent, err := detectConsent(ctx); err != nil {
+		lg.Printf("Failed to check for a cookie banner: %v\n", err)
+	} else if consent.Detected {
+		manifest.Consent = consent
+		if !o.AutoAcceptCookies {
+			lg.Printf("Page shows a cookie consent banner (%s), it may cover the content (use -auto-accept-cookies)\n", consent.Provider)
+		} else if err := acceptConsent(ctx, consent, scrollSettle); err != nil {
+			lg.Printf("Failed to accept cookies: %v\n", err)
+		} else if consent.Clicked == "" {
+			lg.Printf("Page shows a cookie consent banner (%s) but no accept button was found\n", consent.Provider)
+		} else if !consent.Accepted {
+			lg.Printf("Clicked %q but the cookie banner (%s) is still shown\n", consent.Clicked, consent.Provider)
+		} else {
+			lg.infof("Accepted cookies (%s) with %q\n", consent.Provider, consent.Clicked)
+		}
+	}
+
+	// Infinite scroll: load what a visitor scrolling down would see
+	if o.AutoScroll {
+		n, err := autoScroll(ctx, lg, o.AutoScrollMax, o.AutoScrollPause)
+		if err != nil {
+			lg.Printf("Failed to auto scroll: %v\n", err)
+		}
+		manifest.AutoScrolls = n
+		lg.infof("Auto scroll loaded more content %d times\n", n)
+	}
+
+	// Challenge pages are reported as such, not as empty content
+	if report, err := detectCaptcha(ctx); err != nil {
+		lg.Printf("Failed to check for captcha: %v\n", err)
+	} else if report.Detected {
+		manifest.Blocked = "captcha"
+		manifest.Captcha = report
+		lg.Printf("Page is blocked by a captcha (%s), the capture likely shows the challenge, not the page: %s\n", report.Provider, strings.Join(report.Signals, "; "))
+		saveErrorScreenshot(ctx)
+	}
+
+	// Thin pages (redirect stubs, error pages) are only recorded
+	if o.MinTextLength > 0 {
+		if n, err := visibleTextLength(ctx); err != nil {
+			lg.Printf("Failed to measure page text: %v\n", err)
+		} else if n < o.MinTextLength {
+			lg.infof("Page text is %d chars, below -min-text-length %d: skipping capture\n", n, o.MinTextLength)
+			manifest.Skipped = "thin"
+			finishRun()
+			return result, nil
+		}
+	}
+
+	// Embedded apps: extract from inside the frame instead of the top page
+	var frame *cdp.Node
+	if o.Frame != "" {
+		if frame, err = findFrame(ctx, o.Frame); err != nil {
+			err = fmt.Errorf("failed to access frame: %v", err)
+			manifest.Error = err.Error()
+			finishRun()
+			return result, err
+		}
+		lg.infof("Extracting from frame %s\n", frameURL(frame))
+	}
+
+	// Run content retrieval
+	var htmlData string
+	if frame != nil {
+		htmlData, err = frameContent(ctx, frame)
+	} else {
+		htmlData, err = contentRetrieval(ctx, lg, o.Selector)
+	}
+
+	// Get html content
+	if err != nil {
+		lg.Printf("Failed to retrieve content: %v\n", err)
+	} else {
+		if o.StripDataURIs {
+			var stripped []dataURI
+			before := len(htmlData)
+			htmlData, stripped = stripDataURIs(htmlData)
+			lg.infof("Stripped %d data URIs (%d bytes) from the HTML\n", len(stripped), before-len(htmlData))
+			if o.SaveDataURIs && len(stripped) > 0 {
+				if n, err := saveDataURIs(out, "data_uris", stripped); err != nil {
+					lg.Printf("Failed to save data URIs: %v\n", err)
+				} else if n > 0 && !out.disabled {
+					lg.infof("Data URIs saved to %d files in %s\n", n, filepath.Join(out.path, "data_uris"))
+				}
+			}
+		}
+
+		if formatted, err := formatHTML(htmlData, o.HTMLFormat); err != nil {
+			lg.Printf("Failed to format HTML, saving it raw: %v\n", err)
+		} else {
+			htmlData = formatted
+		}
+
+		// Save html within the folder
+		result.HTML = htmlData
+		if savePath, err := out.writeCapped("page.html", []byte(htmlData)); err != nil {
+			lg.Printf("Failed to save HTML file: %v\n", err)
+		} else if savePath != "" {
+			lg.infof("HTML content saved to %s\n", savePath)
+		}
+
+		// Offline copy with local assets, heavier so only on request
+		if o.SelfContained && !out.disabled {
+			base := rawURL
+			if frame != nil {
+				base = frameURL(frame)
+			} else if manifest.FinalURL != "" {
+				base = manifest.FinalURL
+			}
+			n, err := saveOffline(ctx, lg, env.client, env.downloads, out, htmlData, base, userAgent)
+			if err != nil {
+				lg.Printf("Failed to save offline copy: %v\n", err)
+			} else {
+				lg.infof("Offline copy saved to %s with %d assets\n", filepath.Join(out.path, "offline.html"), n)
+			}
+		}
+	}
+
+	// The HTML before JavaScript touched it, next to the rendered page.html
+	if o.RawHTML {
+		if body, err := responseBody(ctx, redirects.documentRequest()); err != nil {
+			lg.Printf("Failed to read the raw HTML response: %v\n", err)
+		} else if savepath, err := out.writeFile("raw.html", body); err != nil {
+			lg.Printf("Failed to save raw HTML: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Raw HTML saved to %s\n", savepath)
+		}
+	}
+
+	// Sizes as rendered, so they match what the screenshot shows
+	if dims, err := extractDimensions(ctx); err != nil {
+		lg.Printf("Failed to read page dimensions: %v\n", err)
+	} else {
+		manifest.Dimensions = dims
+	}
+
+	// Cheap enough to always record, useful as a page speed probe
+	if timing, err := extractLoadTiming(ctx); err != nil {
+		lg.Printf("Failed to read load timing: %v\n", err)
+	} else {
+		manifest.LoadTiming = timing
+		lg.infof("Load timing: %s\n", timing)
+	}
+
+	imgData, err := captureScreenshot(ctx, lg, env.screenshots, o.ScreenshotMode == "viewport", o.ScreenshotFormat, o.ScreenshotQuality, o.ScreenshotTimeout)
+	if err != nil {
+		lg.Printf("Image fault: %v\n", err)
+	} else {
+		result.Screenshot = imgData
+		// Save screenshot within the folder
+		if savepath, err := out.writeCapped(screenshotName(o.ScreenshotFormat), imgData); err != nil {
+			lg.Printf("Failed to save screenshot: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Screenshot saved to %s\n", savepath)
+		}
+	}
+
+	if o.PDF {
+		pdfData, err := capturePDF(ctx, lg, env.screenshots)
+		if err != nil {
+			lg.Printf("PDF fault: %v\n", err)
+		} else if savepath, err := out.writeCapped("page.pdf", pdfData); err != nil {
+			lg.Printf("Failed to save PDF: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("PDF saved to %s\n", savepath)
+		}
+	}
+
+	if o.ScrollTo != "" {
+		imgData, err := captureScrolled(ctx, env.screenshots, o.ScrollTo)
+		if err != nil {
+			lg.Printf("Failed to capture scrolled screenshot: %v\n", err)
+		} else if savepath, err := out.writeCapped("scrolled.png", imgData); err != nil {
+			lg.Printf("Failed to save scrolled screenshot: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Scrolled screenshot saved to %s\n", savepath)
+		}
+	}
+
+	if o.ScreenshotSelector != "" {
+		imgData, err := captureElement(ctx, env.screenshots, o.ScreenshotSelector, o.ScreenshotTimeout)
+		if err != nil {
+			lg.Printf("Failed to capture element screenshot: %v\n", err)
+		} else if savepath, err := out.writeCapped("element.png", imgData); err != nil {
+			lg.Printf("Failed to save element screenshot: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Element screenshot saved to %s\n", savepath)
+		}
+	}
+
+	// Structured head data for tools that don't want to parse page.html
+	if meta, err := extractMetadata(ctx); err != nil {
+		lg.Printf("Failed to extract metadata: %v\n", err)
+	} else {
+		result.Metadata = meta
+		if savepath, err := out.writeJSON("metadata.json", meta); err != nil {
+			lg.Printf("Failed to save metadata: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Metadata saved to %s\n", savepath)
+		}
+	}
+
+	var links []pageLink
+	if frame != nil {
+		links, err = frameLinks(ctx, frame)
+	} else {
+		links, err = extractLinks(ctx, lg, o.PierceShadow)
+	}
+	if err != nil {
+		lg.Printf("Failed to extract links: %v\n", err)
+	} else {
+		// Save links within the folder
+		saveLinks(normalizeLinks(parsedURL, links, o.KeepFragments, o.StripTrailingSlash))
+	}
+
+	// Link checker: the page's own headers and cookies are not sent along
+	if o.CheckLinks && len(result.Links) > 0 {
+		checked := checkLinks(parent, lg, env.client, result.Links, userAgent, o.Delay)
+		broken, unreachable := 0, 0
+		for _, l := range checked {
+			switch {
+			case l.Err != nil:
+				unreachable++
+				lg.debugf("Link %s unreachable: %v", l.URL, l.Err)
+			case l.broken():
+				broken++
+			}
+		}
+		lg.infof("Checked %d links: %d broken (4xx/5xx), %d unreachable\n", len(checked), broken, unreachable)
+		if data, err := linkStatusCSV(checked); err != nil {
+			lg.Printf("Failed to format link status: %v\n", err)
+		} else if savepath, err := out.writeFile("link_status.csv", data); err != nil {
+			lg.Printf("Failed to save link status: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Link status saved to %s\n", savepath)
+		}
+	}
+
+	if o.DownloadImages && !out.disabled {
+		urls, err := extractImageURLs(ctx)
+		if err != nil {
+			lg.Printf("Failed to collect images: %v\n", err)
+		} else if len(urls) > 0 {
+			n := downloadImages(ctx, lg, env.client, env.downloads, out, "images", urls, userAgent)
+			lg.infof("Images saved to %d of %d files in %s\n", n, len(urls), filepath.Join(out.path, "images"))
+		}
+	}
+
+	if newTargets != nil {
+		for i, id := range newTargets.list() {
+			capture, err := scrapeNewTarget(ctx, lg, id, o.PierceShadow)
+			if err != nil {
+				lg.Printf("Failed to scrape new target: %v\n", err)
+				continue
+			}
+			manifest.NewTargets = append(manifest.NewTargets, capture.URL)
+			dir := filepath.Join("new_targets", fmt.Sprint(i+1))
+			if _, err := out.writeCapped(filepath.Join(dir, "page.html"), []byte(capture.HTML)); err != nil {
+				lg.Printf("Failed to save new target HTML: %v\n", err)
+			}
+			if savepath, err := out.writeFile(filepath.Join(dir, "links.txt"), []byte(strings.Join(linkURLs(capture.Links), "\n"))); err != nil {
+				lg.Printf("Failed to save new target links: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("New target %s saved to %s\n", capture.URL, filepath.Join(out.path, dir))
+			}
+		}
+	}
+
+	// Hashes let later runs tell whether the page changed
+	if result.HTML != "" {
+		manifest.HTMLHash = contentHash(result.HTML)
+	}
+	if text, err := extractText(ctx, frame); err != nil {
+		lg.Printf("Failed to extract text: %v\n", err)
+	} else {
+		manifest.TextHash = contentHash(text)
+		result.Text = text
+		// -compare needs text.txt in this run too, for the next comparison
+		if o.Text || (o.Compare != "" && o.DiffMode == "text") {
+			if savepath, err := out.writeFile("text.txt", []byte(text)); err != nil {
+				lg.Printf("Failed to save text: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("Text saved to %d chars in %s\n", len(text), savepath)
+			}
+		}
+		if o.Compare != "" && o.DiffMode == "text" {
+			comparePrevious(lg, previous, text, manifest.TextHash, manifest, out)
+		}
+	}
+	// Template and attribute changes count too, page.html is diffed
+	if o.Compare != "" && o.DiffMode == "html" {
+		if result.HTML == "" {
+			lg.Printf("No HTML captured to compare with %s\n", o.Compare)
+		} else {
+			comparePrevious(lg, previous, result.HTML, manifest.HTMLHash, manifest, out)
+		}
+	}
+
+	if o.Noscript {
+		fallbacks, err := extractNoscript(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract noscript content: %v\n", err)
+		} else {
+			if savepath, err := out.writeFile("noscript.html", []byte(strings.Join(fallbacks, "\n"))); err != nil {
+				lg.Printf("Failed to save noscript content: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("Noscript content saved to %d blocks in %s\n", len(fallbacks), savepath)
+			}
+		}
+	}
+
+	if o.Readability {
+		a, err := extractArticle(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract article: %v\n", err)
+		} else {
+			if savepath, err := out.writeFile("article.html", []byte(a.document())); err != nil {
+				lg.Printf("Failed to save article HTML: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("Article saved to %s\n", savepath)
+			}
+			if savepath, err := out.writeFile("article.txt", []byte(a.Text)); err != nil {
+				lg.Printf("Failed to save article text: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("Article text saved to %d chars in %s\n", len(a.Text), savepath)
+			}
+		}
+	}
+
+	if o.StructuredText {
+		blocks, err := extractStructuredText(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract structured text: %v\n", err)
+		} else if savepath, err := out.writeJSON("structured_text.json", blocks); err != nil {
+			lg.Printf("Failed to save structured text: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Structured text saved to %d blocks in %s\n", len(blocks), savepath)
+		}
+	}
+
+	if len(o.SelectAll) > 0 {
+		fields, err := extractAllFields(ctx, o.SelectAll)
+		if err != nil {
+			lg.Printf("Failed to extract fields: %v\n", err)
+		} else if savepath, err := out.writeJSON("fields.json", fields); err != nil {
+			lg.Printf("Failed to save fields: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Fields saved to %s\n", savepath)
+		}
+	}
+
+	if o.Breadcrumbs {
+		trail, err := extractBreadcrumbs(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract breadcrumbs: %v\n", err)
+		} else if len(trail.Items) == 0 {
+			lg.infof("No breadcrumb trail found.")
+		} else if savepath, err := out.writeJSON("breadcrumbs.json", trail); err != nil {
+			lg.Printf("Failed to save breadcrumbs: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Breadcrumbs saved to %d items (%s) in %s\n", len(trail.Items), trail.Source, savepath)
+		}
+	}
+
+	if o.Forms {
+		forms, err := extractForms(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract forms: %v\n", err)
+		} else if savepath, err := out.writeJSON("forms.json", forms); err != nil {
+			lg.Printf("Failed to save forms: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Forms saved to %d forms in %s\n", len(forms), savepath)
+		}
+	}
+
+	if o.Hreflang {
+		info, err := extractHreflang(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract hreflang: %v\n", err)
+		} else {
+			if info.MissingXDefault {
+				lg.Println("hreflang alternates found but no x-default is declared")
+			}
+			if savepath, err := out.writeJSON("hreflang.json", info); err != nil {
+				lg.Printf("Failed to save hreflang: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("hreflang saved to %d alternates in %s\n", len(info.Alternates), savepath)
+			}
+		}
+	}
+
+	if o.DOMJSON {
+		tree, err := extractDOMTree(ctx)
+		if err != nil {
+			lg.Printf("Failed to serialize DOM: %v\n", err)
+		} else {
+			if tree.Truncated {
+				lg.Println("DOM is too large, dom.json is truncated")
+			}
+			if savepath, err := out.writeJSON("dom.json", tree); err != nil {
+				lg.Printf("Failed to save DOM tree: %v\n", err)
+			} else if savepath != "" {
+				lg.infof("DOM tree saved to %s\n", savepath)
+			}
+		}
+	}
+
+	if o.ExtractPrices {
+		prices, err := extractPrices(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract prices: %v\n", err)
+		} else if savepath, err := out.writeJSON("prices.json", prices); err != nil {
+			lg.Printf("Failed to save prices: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("%d prices saved to %s\n", len(prices), savepath)
+		}
+	}
+
+	if o.StructuredData {
+		items, skipped, err := extractStructuredData(ctx)
+		for _, err := range skipped {
+			lg.Printf("Skipping structured data: %v\n", err)
+		}
+		if err != nil {
+			lg.Printf("Failed to extract structured data: %v\n", err)
+		} else if savepath, err := out.writeJSON("structured_data.json", items); err != nil {
+			lg.Printf("Failed to save structured data: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("%d structured data items saved to %s\n", len(items), savepath)
+		}
+	}
+
+	if o.Pagination {
+		state, err := extractPagination(ctx)
+		if err != nil {
+			lg.Printf("Failed to extract pagination: %v\n", err)
+		} else if !state.Detected {
+			lg.infof("No pagination detected.")
+		} else if savepath, err := out.writeJSON("pagination.json", state); err != nil {
+			lg.Printf("Failed to save pagination: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Pagination saved to %s (page %d of %d)\n", savepath, state.Current, state.Total)
+		}
+	}
+
+	if o.DetectGates {
+		report, err := detectGates(ctx)
+		if err != nil {
+			lg.Printf("Failed to detect gates: %v\n", err)
+		} else {
+			manifest.Gate = report
+			if report.Gated {
+				lg.Printf("Page looks gated: %s\n", strings.Join(report.Reasons, "; "))
+			}
+		}
+	}
+
+	if o.DOMStats {
+		stats, err := extractDOMStats(ctx)
+		if err != nil {
+			lg.Printf("Failed to compute DOM stats: %v\n", err)
+		} else {
+			manifest.DOM = stats
+			lg.infof("DOM: %d nodes, max depth %d\n", stats.Nodes, stats.MaxDepth)
+		}
+	}
+
+	// Tags are decided once all content has been extracted
+	if env.tagRules != nil {
+		tags, err := applyTagRules(ctx, env.tagRules)
+		if err != nil {
+			lg.Printf("Failed to apply tag rules: %v\n", err)
+		} else {
+			manifest.Tags = tags
+			lg.infof("Matched tags: %s\n", strings.Join(tags, ", "))
+		}
+	}
+
+	all := responses.list()
+	manifest.BytesDownloaded = responses.transferred()
+	lg.infof("Network responses: %d (%s)\n", len(all), statusClassSummary(all))
+	if savepath, err := out.writeJSON("network.json", all); err != nil {
+		lg.Printf("Failed to save network responses: %v\n", err)
+	} else if savepath != "" {
+		lg.infof("Network responses saved to %s\n", savepath)
+	}
+
+	if timings != nil {
+		slow := timings.slowest(o.SlowRequests)
+		if savepath, err := out.writeJSON("slow_requests.json", slow); err != nil {
+			lg.Printf("Failed to save slow requests: %v\n", err)
+		} else if savepath != "" {
+			lg.infof("Slowest %d requests saved to %s\n", len(slow), savepath)
+		}
+	}
+
+	if o.JSONOutput || env.outputTemplate != nil {
+		if err := chromedp.Run(ctx, chromedp.Title(&result.Title)); err != nil {
+			lg.Printf("Failed to read title: %v\n", err)
+		}
+	}
+
+	// Measured while Chrome is still running
+	manifest.Resources = collectResources(ctx)
+
+	if hosts := relaxed.list(); len(hosts) > 0 {
+		lg.infof("Relaxed TLS was used for: %s\n", strings.Join(hosts, ", "))
+		for _, host := range hosts {
+			if !o.relaxedTLSAllowed(host) {
+				lg.Printf("Resources of %s were loaded despite a certificate error, the override for %s is browser wide\n", host, hostname)
+			}
+		}
+	}
+
+	if env.db != nil {
+		record, err := extractPageRecord(ctx)
+		if err != nil {
+			lg.Printf("Failed to read page for SQLite: %v\n", err)
+		} else {
+			record.URL = rawURL
+			record.Status = statusCode
+			record.LinksCount = manifest.LinksCount
+			record.ScrapedAt = manifest.Timestamp
+			if err := env.db.upsert(record); err != nil {
+				lg.Printf("Failed to store page in SQLite: %v\n", err)
+			} else {
+				lg.infof("Page stored in %s as %s\n", o.SQLite, record.CanonicalURL)
+			}
+		}
+	}
+
+	finishRun()
+	return result, nil
+}
+
+// Compare the page text, or its HTML in html mode, with the earlier run
+// and save diff.txt when it changed. An unreadable earlier run is logged,
+// the page counts as unchanged.
+func comparePrevious(lg *logger, prev previousRun, text, hash string, manifest *RunManifest, out *outputDir) {
+	if prev.err != nil {
+		lg.Printf("Failed to load the run to compare with: %v\n", prev.err)
+		return
+	}
+	dir, prevHash, prevText := prev.dir, prev.hash, prev.text
+	manifest.ComparedWith = dir
+	manifest.DiffMode = prev.mode
+	if prevHash == hash {
+		manifest.Changed = false
+		lg.infof("Page unchanged since %s\n", dir)
+		return
+	}
+	manifest.Changed = true
+	if prevText == "" {
+		lg.infof("Page changed since %s (no %s there to diff against)\n", dir, prev.file)
+		return
+	}
+	diff := diffLines(prevText, text)
+	lg.infof("Page changed since %s: %s\n", dir, diffStat(diff))
+	if savepath, err := out.writeFile("diff.txt", []byte(strings.Join(diff, "\n"))); err != nil {
+		lg.Printf("Failed to save diff: %v\n", err)
+	} else if savepath != "" {
+		lg.infof("Diff saved to %s\n", savepath)
+	}
+}
+
+// Launch a browser and open a tab with the navigation timeout; the
+// returned cancel closes both
+func newBrowser(parent context.Context, lg *logger, opts []chromedp.ExecAllocatorOption, headless bool, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if !headless {
+		// A false flag drops --headless from the defaults
+		opts = append(opts[:len(opts):len(opts)], chromedp.Flag("headless", false))
+	}
+
+	// Setting up allocator context
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(parent, opts...)
+
+	// Create context with the allocator, tracing the protocol with -verbose
+	var ctxOpts []chromedp.ContextOption
+	if lg.level == LevelDebug {
+		ctxOpts = append(ctxOpts, chromedp.WithDebugf(lg.debugf))
+	}
+	ctx, cancelCtx := chromedp.NewContext(allocCtx, ctxOpts...)
+
+	// For secure browsing, set timeout
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+
+	return ctx, func() {
+		cancelTimeout()
+		cancelCtx()
+		cancelAlloc()
+	}
+}
+
+func contentRetrieval(ctx context.Context, lg *logger, selector string) (string, error) {
+	var htmlContent string
+
+	// Get everything tagged with <html>, or just the selected element
+	if selector == "" {
+		selector = "html"
+	} else if found, err := elementExists(ctx, selector); err != nil {
+		return "", fmt.Errorf("error checking selector %q: %v", selector, err)
+	} else if !found {
+		// OuterHTML would wait for the element until the run times out
+		return "", fmt.Errorf("selector %q matched no element", selector)
+	}
+	err := chromedp.Run(ctx, chromedp.OuterHTML(selector, &htmlContent, chromedp.ByQuery))
+	// Handle error
+	if err != nil {
+		lg.Printf("Error retrieving content: %v", err)
+	}
+
+	return htmlContent, err
+}
+
+func captureScreenshot(ctx context.Context, lg *logger, slots semaphore, viewport bool, format string, quality int, timeout time.Duration) ([]byte, error) {
+	// The image is formed using zeros and ones.
+	var screenShotBuffer []byte
+
+	// Wait for a free slot before encoding the image
+	if err := slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer slots.release()
+
+	// A huge page can take forever to encode, give up on the image
+	// without using up the time left for the rest of the capture
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Take full page ss, or only what is visible in the window
+	// PNG is lossless, JPEG takes the picture quality 0 - 100
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		shot := page.CaptureScreenshot()
+		if format == "jpeg" {
+			shot = shot.WithFormat(page.CaptureScreenshotFormatJpeg).WithQuality(int64(quality))
+		}
+		if !viewport {
+			// The whole document, as FullScreenshot does it
+			_, _, _, _, _, content, err := page.GetLayoutMetrics().Do(ctx)
+			if err != nil {
+				return err
+			}
+			shot = shot.WithCaptureBeyondViewport(true).WithFromSurface(true).WithClip(&page.Viewport{
+				Width:  content.Width,
+				Height: content.Height,
+				Scale:  1,
+			})
+		}
+		var err error
+		screenShotBuffer, err = shot.Do(ctx)
+		return err
+	}))
+
+	// Handle error
+	if err != nil {
+		lg.Printf("Error capturing screenshot: %v", err)
+	}
+
+	return screenShotBuffer, err
+}
+
+// screenshot.png, or screenshot.jpg for JPEG
+func screenshotName(format string) string {
+	if format == "jpeg" {
+		return "screenshot.jpg"
+	}
+	return "screenshot.png"
+}
+
+func capturePDF(ctx context.Context, lg *logger, slots semaphore) ([]byte, error) {
+	var pdfBuffer []byte
+
+	// Printing is as heavy as a screenshot and shares its slots
+	if err := slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer slots.release()
+
+	// A4 portrait in inches, with the page's background colors
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		pdfBuffer, _, err = page.PrintToPDF().
+			WithPrintBackground(true).
+			WithPaperWidth(8.27).
+			WithPaperHeight(11.69).
+			Do(ctx)
+		return err
+	}))
+
+	// Handle error
+	if err != nil {
+		lg.Printf("Error capturing PDF: %v", err)
+	}
+
+	return pdfBuffer, err
+}
+
+// Screenshot for debugging a failed step. The step may have used up the
+// run's deadline, so this runs on its own short timeout.
+func captureErrorScreenshot(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	var buf []byte
+	err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf))
+	return buf, err
+}
+
+// How long lazy content gets to render after scrolling
+const scrollSettle = 500 * time.Millisecond
+
+// Scroll to an element (or a Y offset when target is a number) and take
+// a viewport screenshot
+func captureScrolled(ctx context.Context, slots semaphore, target string) ([]byte, error) {
+	javascript := fmt.Sprintf("window.scrollTo(0, %s), true", target)
+	if _, err := strconv.Atoi(target); err != nil {
+		// Missing elements fail right away instead of waiting for them
+		selectorJSON, _ := json.Marshal(target)
+		javascript = fmt.Sprintf(`(() => {
+			const el = document.querySelector(%s);
+			if (el) el.scrollIntoView({ block: 'start' });
+			return !!el;
+		})()`, selectorJSON)
+	}
+	var found bool
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &found)); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no element matches %q", target)
+	}
+
+	if err := slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer slots.release()
+
+	var buf []byte
+	err := chromedp.Run(ctx, chromedp.Sleep(scrollSettle), chromedp.CaptureScreenshot(&buf))
+	return buf, err
+}
+
+// Just the first element matching selector, scrolled into view so lazy
+// content inside it has rendered
+func captureElement(ctx context.Context, slots semaphore, selector string, timeout time.Duration) ([]byte, error) {
+	// Missing elements fail right away instead of waiting for them
+	found, err := elementExists(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no element matches %q", selector)
+	}
+
+	if err := slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer slots.release()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var buf []byte
+	err = chromedp.Run(ctx,
+		chromedp.ScrollIntoView(selector, chromedp.ByQuery),
+		chromedp.Sleep(scrollSettle),
+		chromedp.Screenshot(selector, &buf, chromedp.ByQuery),
+	)
+	return buf, err
+}
+
+func extractLinks(ctx context.Context, lg *logger, pierceShadow bool) ([]pageLink, error) {
+	var jsonResult string
+	// JavaScript to extract all href attributes from <a> tags with their visible text
+	// a little vast because sometimes href is object for SVG links
+	javascript := `(() => {` + shadowQueryAllJS(pierceShadow) + `
+	return JSON.stringify({base: document.baseURI, links: queryAll('a').map(a => {
+		let href = a.href;
+		if (typeof href === 'object' && href !== null) {
+			href = href.baseVal; // SVG linkleri için
+		}
+		const text = (a.innerText !== undefined ? a.innerText : a.textContent) || '';
+		return {url: href, text: text.trim()};
+	}).filter(l => typeof l.url === 'string' && l.url !== "")})
+	})()`
+	// Evaluate the JavaScript in the page context
+	err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &jsonResult))
+	if err != nil {
+		return nil, fmt.Errorf("error extracting links: %v", err)
+	}
+	//unpack the JSON string into a Go slice
+	var found struct {
+		Base  string     `json:"base"`
+		Links []pageLink `json:"links"`
+	}
+	err = json.Unmarshal([]byte(jsonResult), &found)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling JSON: %v", err)
+	}
+	// SVG baseVal is the raw attribute, so resolve against the document's
+	// base URL (final URL after redirects, or <base href>)
+	base, _ := url.Parse(found.Base)
+	links := []pageLink{}
+	for _, l := range found.Links {
+		ref, err := url.Parse(strings.TrimSpace(l.URL))
+		if err != nil {
+			lg.debugf("Skipping malformed link %q: %v", l.URL, err)
+			continue
+		}
+		if base != nil && !ref.IsAbs() {
+			ref = base.ResolveReference(ref)
+		}
+		links = append(links, pageLink{URL: ref.String(), Text: l.Text})
+	}
+	return links, nil
+}
+
+// Visible text of the page body (innerText skips script and style), or of
+// the frame's body when frame is set
+func extractText(ctx context.Context, frame *cdp.Node) (string, error) {
+	var text string
+	opts := []chromedp.QueryOption{chromedp.ByQuery, chromedp.NodeReady}
+	if frame != nil {
+		opts = append(opts, chromedp.FromNode(frame))
+	}
+	if err := chromedp.Run(ctx, chromedp.Text("body", &text, opts...)); err != nil {
+		return "", fmt.Errorf("error extracting text: %v", err)
+	}
+	return cleanText(text), nil
+}
+
+// Collapse runs of spaces inside lines and runs of blank lines
+func cleanText(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func extractNoscript(ctx context.Context) ([]string, error) {
+	var blocks []string
+	// With scripting on the browser keeps <noscript> bodies as raw markup text
+	javascript := `Array.from(document.querySelectorAll('noscript')).map((n, i) =>
+		'<!-- noscript ' + (i + 1) + ' -->\n' + n.innerHTML.trim())`
+	err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &blocks))
+	if err != nil {
+		return nil, fmt.Errorf("error extracting noscript: %v", err)
+	}
+	return blocks, nil
+}
+
+// Browser identities tried in order by -retry-different-ua, the first one
+// is the default
+var userAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, " +
+		"like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, " +
+		"like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, " +
+		"like Gecko) Version/17.2 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, " +
+		"like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+}
+
+// Status codes that usually mean the client was blocked
+func isBlockingStatus(code int64) bool {
+	return code == 403 || code == 429
+}
+
+// Pages with less visible text than this look blank
+const blankTextThreshold = 50
+
+func visibleTextLength(ctx context.Context) (int, error) {
+	var length int
+	err := chromedp.Run(ctx, chromedp.Evaluate(`document.body ? document.body.innerText.trim().length : 0`, &length))
+	return length, err
+}
+
+// Network request status code analysis
+func listNetworkRequests(lg *logger, code int64, text string) {
+	if code == 0 {
+		return
+	}
+	lg.infof("Request network: %d (%s)\n", code, text)
+	switch {
+	case code >= 200 && code < 300:
+		lg.infof("Request SUCCESSFUL: Site is accessible.")
+	case code >= 300 && code < 400:
+		lg.infof("Request REDIRECTION (%d): Site is redirecting to another address.\n", code)
+	case code == 403:
+		lg.Println("Request FORBIDDEN (403): Access denied (WAF or Bot Protection).")
+	case code == 404:
+		lg.Println("Request NOT FOUND (404): Page does not exist.")
+	case code >= 400 && code < 500:
+		lg.Printf("Request CLIENT ERROR (%d): %s\n", code, text)
+	case code >= 500:
+		lg.Printf("Request SERVER ERROR (%d): Target site is down or faulty.\n", code)
+	default:
+		lg.Printf("Request UNKNOWN STATUS: %d\n", code)
+	}
+}
+
+// Split a comma separated flag value, dropping empty entries
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// hostAllowed matches a host against exact names and "*.suffix" patterns
+func hostAllowed(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// A response over https that is not marked secure got past a certificate error
+func isRelaxedResponse(resp *network.Response) bool {
+	if !strings.HasPrefix(resp.URL, "https://") {
+		return false
+	}
+	return resp.SecurityState == security.StateInsecure || resp.SecurityState == security.StateInsecureBroken
+}
+
+// Concurrency safe set of host names, listeners run on their own goroutine
+type hostSet struct {
+	mu    sync.Mutex
+	hosts map[string]bool
+}
+
+func newHostSet() *hostSet {
+	return &hostSet{hosts: make(map[string]bool)}
+}
+
+func (s *hostSet) add(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts[host] = true
+}
+
+// Sorted copy of the hosts
+func (s *hostSet) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hosts := make([]string, 0, len(s.hosts))
+	for host := range s.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Chrome for the browser tests, $CHROME_PATH or the first one in PATH.
+// The tests are skipped where there is none.
+func chromePath(t *testing.T) string {
+	t.Helper()
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path
+	}
+	for _, name := range []string{"headless-shell", "chromium", "chromium-browser", "google-chrome", "google-chrome-stable"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	t.Skip("no Chrome or Chromium in PATH")
+	return ""
+}
+
+// Log lines end up in the test output
+type testWriter struct{ t *testing.T }
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// What the CLI defaults to, writing into a temporary folder
+func testOptions(t *testing.T) Options {
+	return Options{
+		Out:          t.TempDir(),
+		ChromePath:   chromePath(t),
+		Timeout:      30 * time.Second,
+		IgnoreRobots: true,
+		LinksFormat:  "txt",
+		LogOutput:    testWriter{t},
+	}
+}
+
+func scrapeURL(t *testing.T, o Options, rawURL string) (*Result, error) {
+	t.Helper()
+	s, err := New(o)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+	return s.Scrape(context.Background(), rawURL)
+}
+
+// Serves the same page on every path
+func fixtureServer(t *testing.T, page string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestListNetworkRequests(t *testing.T) {
+	tests := []struct {
+		code  int64
+		level LogLevel
+		want  string
+	}{
+		{200, LevelInfo, "Request SUCCESSFUL"},
+		{301, LevelInfo, "Request REDIRECTION (301)"},
+		{403, LevelInfo, "Request FORBIDDEN (403)"},
+		{404, LevelInfo, "Request NOT FOUND (404)"},
+		{418, LevelInfo, "Request CLIENT ERROR (418): I'm a teapot"},
+		{503, LevelInfo, "Request SERVER ERROR (503)"},
+		// Errors are printed even with -quiet, success isn't
+		{404, LevelError, "Request NOT FOUND (404)"},
+		{200, LevelError, ""},
+		{0, LevelInfo, ""},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		listNetworkRequests(newLogger(&buf, tt.level), tt.code, http.StatusText(int(tt.code)))
+		got := buf.String()
+		if tt.want == "" {
+			if got != "" {
+				t.Errorf("%d at level %d printed %q, want nothing", tt.code, tt.level, got)
+			}
+			continue
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%d at level %d printed %q, want %q", tt.code, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestScrapeRecordsStatusOfSingleNavigation(t *testing.T) {
+	o := testOptions(t)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<html><body><h1>Gone</h1><a href="/home">Home</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	result, err := scrapeURL(t, o, srv.URL+"/missing")
+	if err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+	if result.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", result.StatusCode, http.StatusNotFound)
+	}
+	if result.Manifest.StatusCode != http.StatusNotFound {
+		t.Errorf("manifest status = %d, want %d", result.Manifest.StatusCode, http.StatusNotFound)
+	}
+	// The response listener sees the one navigation, there is no second load
+	if n := hits.Load(); n != 1 {
+		t.Errorf("page was requested %d times, want 1", n)
+	}
+}
@@ -0,0 +1,32 @@
+package scraper
+
+import "context"
+
+// Counting semaphore, a nil semaphore never blocks
+type semaphore chan struct{}
+
+// n <= 0 means unlimited
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
@@ -0,0 +1,22 @@
+package scraper
+
+import "fmt"
+
+// JavaScript helper that defines queryAll(selector). When pierce is true it
+// also walks every open shadow root recursively. Closed shadow roots are not
+// reachable from page scripts, so their content is always missed.
+func shadowQueryAllJS(pierce bool) string {
+	return fmt.Sprintf(`const pierceShadow = %t;
+	const queryAll = (selector) => {
+		if (!pierceShadow) return Array.from(document.querySelectorAll(selector));
+		const found = [];
+		const walk = (root) => {
+			found.push(...root.querySelectorAll(selector));
+			for (const el of root.querySelectorAll('*')) {
+				if (el.shadowRoot) walk(el.shadowRoot);
+			}
+		};
+		walk(document);
+		return found;
+	};`, pierce)
+}
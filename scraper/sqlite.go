@@ -0,0 +1,86 @@
+package scraper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+const pageSchema = `CREATE TABLE IF NOT EXISTS pages (
+	canonical_url TEXT PRIMARY KEY,
+	url           TEXT NOT NULL,
+	status        INTEGER NOT NULL,
+	title         TEXT,
+	text          TEXT,
+	links_count   INTEGER NOT NULL,
+	scraped_at    TEXT NOT NULL
+)`
+
+// Core data of one scraped page as stored in SQLite
+type pageRecord struct {
+	CanonicalURL string `json:"canonical"`
+	URL          string `json:"-"`
+	Status       int64  `json:"-"`
+	Title        string `json:"title"`
+	Text         string `json:"text"`
+	LinksCount   int    `json:"-"`
+	ScrapedAt    string `json:"-"`
+}
+
+type pageStore struct {
+	*sql.DB
+}
+
+// Open the database and create the schema on first use
+func openPageStore(path string) (*pageStore, error) {
+	if sqliteDriver == "" {
+		return nil, errors.New("this build has no SQLite support, build with CGO_ENABLED=1")
+	}
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite takes one writer at a time, parallel pages wait here instead
+	// of failing with "database is locked"
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(pageSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating schema: %v", err)
+	}
+	return &pageStore{db}, nil
+}
+
+// Insert the page, or replace the earlier row for the same canonical URL
+func (s *pageStore) upsert(r *pageRecord) error {
+	_, err := s.Exec(`INSERT INTO pages (canonical_url, url, status, title, text, links_count, scraped_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(canonical_url) DO UPDATE SET
+			url = excluded.url,
+			status = excluded.status,
+			title = excluded.title,
+			text = excluded.text,
+			links_count = excluded.links_count,
+			scraped_at = excluded.scraped_at`,
+		r.CanonicalURL, r.URL, r.Status, r.Title, r.Text, r.LinksCount, r.ScrapedAt)
+	return err
+}
+
+// Title, visible text and canonical URL (falling back to the page URL)
+func extractPageRecord(ctx context.Context) (*pageRecord, error) {
+	javascript := `(() => {
+		const link = document.querySelector('link[rel="canonical"]');
+		return {
+			canonical: (link && link.href) || location.href,
+			title: document.title,
+			text: document.body ? document.body.innerText : '',
+		};
+	})()`
+	var record pageRecord
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &record)); err != nil {
+		return nil, fmt.Errorf("error reading page record: %v", err)
+	}
+	return &record, nil
+}
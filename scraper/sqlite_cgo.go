@@ -0,0 +1,8 @@
+//go:build cgo
+
+package scraper
+
+import _ "github.com/mattn/go-sqlite3" // registers the "sqlite3" driver
+
+// The driver is cgo only, builds without cgo leave -sqlite out
+const sqliteDriver = "sqlite3"
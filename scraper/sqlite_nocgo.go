@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package scraper
+
+// Built with CGO_ENABLED=0, there is no SQLite driver
+const sqliteDriver = ""
@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Crawls save their progress at most this often, and once when they end
+const crawlStateInterval = 5 * time.Second
+
+// Progress of a crawl in crawl_state.json, what -resume continues from
+type crawlState struct {
+	// Pages still to scrape, the ones in flight at the time included
+	Queue []stateItem `json:"queue"`
+	// Pages that finished, with the run folder they were saved to
+	Pages []stateItem `json:"pages"`
+	// Every URL ever queued, as crawlKey
+	Visited []string `json:"visited"`
+	// Counted against -max-pages
+	Scheduled int `json:"scheduled"`
+}
+
+type stateItem struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth,omitempty"`
+	Dir   string `json:"dir,omitempty"`
+	// The run finished without an error or interruption, and wrote these
+	// files into Dir. Kept here since a -compact-manifest leaves them out.
+	Complete bool     `json:"complete,omitempty"`
+	Files    []string `json:"files,omitempty"`
+}
+
+func loadCrawlState(path string) (*crawlState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st crawlState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	return &st, nil
+}
+
+// Written to a temp file and renamed, a crash mid-write keeps the last
+// complete state
+func saveCrawlState(path string, st *crawlState, dirPerm, filePerm os.FileMode) error {
+	sort.Strings(st.Visited)
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), filePerm)
+}
+
+// A page of a resumed crawl is only skipped when its run completed and
+// every file it wrote is still there
+func scrapedBefore(p stateItem) bool {
+	if !p.Complete || p.Dir == "" {
+		return false
+	}
+	for _, name := range append(p.Files, "manifest.json") {
+		if _, err := os.Stat(filepath.Join(p.Dir, filepath.FromSlash(name))); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Below this many links a script heavy page is assumed to render client side
+const staticMinLinks = 5
+
+// Result of fetching a page without the browser
+type staticPage struct {
+	Status int
+	Links  []pageLink
+	// Title, description, canonical and og:* / twitter:* tags
+	Metadata map[string]string
+	// The page looks like it needs JavaScript to render its links
+	NeedsJS bool
+}
+
+// Fetch rawURL with net/http and collect its anchors in document order.
+// Basic auth is sent when user is set; net/http drops it on redirects to
+// other hosts.
+func fetchStaticLinks(client *http.Client, rawURL, userAgent string, headers map[string]string, user, pass string) (*staticPage, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if user != "" {
+		req.SetBasicAuth(user, pass)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return nil, fmt.Errorf("not an HTML page (%s)", ct)
+	}
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	// Links resolve against the final URL after redirects
+	page, err := parseStaticPage(io.LimitReader(body, 32<<20), resp.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	page.Status = resp.StatusCode
+	return page, nil
+}
+
+// Anchors and head metadata of an HTML document served at base. Relative
+// hrefs resolve against base, or <base href> when the page has one.
+func parseStaticPage(r io.Reader, base *url.URL) (*staticPage, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %v", err)
+	}
+
+	var anchors []pageLink
+	var canonical string
+	meta := map[string]string{}
+	// Same as extractMetadata: first non-empty value wins
+	set := func(key, value string) {
+		value = strings.Join(strings.Fields(value), " ")
+		if _, ok := meta[key]; value != "" && !ok {
+			meta[key] = value
+		}
+	}
+	scripts, scriptBytes, textBytes := 0, 0, 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "base":
+				if href := attr(n, "href"); href != "" {
+					if u, err := base.Parse(href); err == nil {
+						base = u
+					}
+				}
+			case "a":
+				if href := strings.TrimSpace(attr(n, "href")); href != "" {
+					anchors = append(anchors, pageLink{URL: href, Text: nodeText(n)})
+				}
+			case "title":
+				if n.Namespace == "" {
+					set("title", nodeText(n))
+				}
+			case "meta":
+				name := strings.ToLower(strings.TrimSpace(attr(n, "name")))
+				if name == "description" {
+					set(name, attr(n, "content"))
+				}
+				key := strings.ToLower(strings.TrimSpace(attr(n, "property")))
+				if key == "" {
+					key = name
+				}
+				if strings.HasPrefix(key, "og:") || strings.HasPrefix(key, "twitter:") {
+					set(key, attr(n, "content"))
+				}
+			case "link":
+				if canonical == "" && hasToken(attr(n, "rel"), "canonical") {
+					canonical = strings.TrimSpace(attr(n, "href"))
+				}
+			case "script":
+				scripts++
+				if n.FirstChild != nil {
+					scriptBytes += len(n.FirstChild.Data)
+				}
+				return
+			case "style":
+				return
+			}
+		} else if n.Type == html.TextNode {
+			textBytes += len(strings.TrimSpace(n.Data))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	page := &staticPage{Metadata: meta}
+	for _, a := range anchors {
+		u, err := base.Parse(a.URL)
+		if err != nil {
+			continue
+		}
+		page.Links = append(page.Links, pageLink{URL: u.String(), Text: a.Text})
+	}
+	if canonical != "" {
+		if u, err := base.Parse(canonical); err == nil {
+			set("canonical", u.String())
+		}
+	}
+	page.NeedsJS = len(page.Links) < staticMinLinks && (scripts >= 3 || scriptBytes > textBytes)
+	return page, nil
+}
+
+// Whether the space separated list (a rel attribute) holds token
+func hasToken(list, token string) bool {
+	for _, f := range strings.Fields(list) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text below n, roughly what innerText gives for an anchor
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// Only plain http(s) pages can take the fast path
+func isHTTPURL(u *url.URL) bool {
+	return u.Scheme == "http" || u.Scheme == "https"
+}
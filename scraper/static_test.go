@@ -0,0 +1,214 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseFixture(t *testing.T, page, rawURL string) *staticPage {
+	t.Helper()
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseStaticPage(strings.NewReader(page), base)
+	if err != nil {
+		t.Fatalf("parseStaticPage: %v", err)
+	}
+	return parsed
+}
+
+func TestParseStaticPageLinks(t *testing.T) {
+	pages, pseudo := hrefsFixtureWant("http://example.com")
+	tests := []struct {
+		name, page string
+		want       []string
+	}{
+		{
+			"relative and absolute, anchors without href skipped",
+			`<a href="/one">One</a><a href="two">Two</a><a href="https://example.org/three">Three</a><a>No href</a>`,
+			[]string{"http://example.com/one", "http://example.com/dir/sub/two", "https://example.org/three"},
+		},
+		{
+			"every kind of href",
+			hrefsFixture,
+			append(pages, pseudo...),
+		},
+		{
+			"SVG anchor",
+			`<svg><a href="../svg"><text>SVG</text></a></svg>`,
+			[]string{"http://example.com/dir/svg"},
+		},
+		{
+			"base href",
+			`<head><base href="https://cdn.example.com/root/"></head><a href="x">X</a><a href="/y">Y</a>`,
+			[]string{"https://cdn.example.com/root/x", "https://cdn.example.com/y"},
+		},
+		{
+			"blank href and duplicates kept in document order",
+			`<a href="  ">Blank</a><a href="/b">B</a><a href="/a">A</a><a href="/b">B again</a>`,
+			[]string{"http://example.com/b", "http://example.com/a", "http://example.com/b"},
+		},
+		{
+			"anchors in scripts ignored",
+			`<script>document.write('<a href="/hidden">x</a>')</script><a href="/shown">Shown</a>`,
+			[]string{"http://example.com/shown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := linkURLs(parseFixture(t, tt.page, "http://example.com/dir/sub/page").Links)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("links = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStaticPageLinkText(t *testing.T) {
+	page := parseFixture(t, `<a href="/a">  One
+	<b>bold</b> </a><a href="/b"><img alt="logo"></a>`, "https://example.com/")
+	want := []pageLink{
+		{URL: "https://example.com/a", Text: "One bold"},
+		{URL: "https://example.com/b", Text: ""},
+	}
+	if !reflect.DeepEqual(page.Links, want) {
+		t.Errorf("links = %v, want %v", page.Links, want)
+	}
+}
+
+func TestParseStaticPageMetadata(t *testing.T) {
+	tests := []struct {
+		name, page string
+		want       map[string]string
+	}{
+		{
+			"head fields",
+			`<html><head>
+<title> Fixture
+ page </title>
+<meta name="Description" content="What the page is about">
+<link rel="canonical" href="/canonical">
+<meta property="og:title" content="Open Graph title">
+<meta name="twitter:card" content="summary">
+<meta name="keywords" content="left out">
+</head><body></body></html>`,
+			map[string]string{
+				"title":        "Fixture page",
+				"description":  "What the page is about",
+				"canonical":    "https://example.com/canonical",
+				"og:title":     "Open Graph title",
+				"twitter:card": "summary",
+			},
+		},
+		{
+			"first non-empty value wins",
+			`<meta property="og:title" content=""><meta property="og:title" content="First"><meta property="og:title" content="Second">`,
+			map[string]string{"og:title": "First"},
+		},
+		{
+			"property before name, keys lowercased",
+			`<meta property="OG:Type" name="twitter:card" content="article">`,
+			map[string]string{"og:type": "article"},
+		},
+		{
+			"canonical is one rel token of several, against base href",
+			`<head><base href="https://example.org/b/"><link rel="alternate CANONICAL" href="page"></head>`,
+			map[string]string{"canonical": "https://example.org/b/page"},
+		},
+		{
+			"SVG title is not the page title",
+			`<body><svg><title>Icon</title></svg></body>`,
+			map[string]string{},
+		},
+		{
+			"nothing",
+			`<p>Just text</p>`,
+			map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseFixture(t, tt.page, "https://example.com/page").Metadata
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadata = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStaticPageNeedsJS(t *testing.T) {
+	links := strings.Repeat(`<a href="/x">x</a>`, staticMinLinks)
+	tests := []struct {
+		name, page string
+		want       bool
+	}{
+		{"static page", `<p>Some text</p><a href="/a">A</a>`, false},
+		{"three scripts", `<script></script><script></script><script></script><a href="/a">A</a>`, true},
+		{"more script than text", `<div id="app"></div><script>render(document.getElementById('app'))</script>`, true},
+		{"enough links despite scripts", `<script></script><script></script><script></script>` + links, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFixture(t, tt.page, "https://example.com/").NeedsJS; got != tt.want {
+				t.Errorf("NeedsJS = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticScrapeWritesLinksAndMetadata(t *testing.T) {
+	srv := fixtureServer(t, `<html><head><title>Links</title></head><body>
+<a href="/b">B</a>
+<a href="/a#top"></a>
+<a href="/a">A</a>
+<a href="b">B again</a>
+<a href="mailto:someone@example.com">Mail</a>
+<a href="javascript:void(0)">Script</a>
+</body></html>`)
+	o := staticCrawlOptions(t)
+	o.PseudoLinks = true
+
+	result, err := scrapeURL(t, o, srv.URL+"/")
+	if err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+	if result.Manifest.Mode != "static" {
+		t.Fatalf("mode = %q, want static", result.Manifest.Mode)
+	}
+
+	want := []string{srv.URL + "/a", srv.URL + "/b"}
+	if !reflect.DeepEqual(result.Links, want) {
+		t.Errorf("links = %v, want %v", result.Links, want)
+	}
+	files := map[string]string{
+		"links.txt":        strings.Join(want, "\n"),
+		"pseudo_links.txt": "javascript:void(0)\nmailto:someone@example.com",
+	}
+	for name, want := range files {
+		data, err := os.ReadFile(filepath.Join(result.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(result.Dir, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta map[string]string
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if wantMeta := map[string]string{"title": "Links"}; !reflect.DeepEqual(meta, wantMeta) {
+		t.Errorf("metadata.json = %v, want %v", meta, wantMeta)
+	}
+}
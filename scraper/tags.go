@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/chromedp/chromedp"
+)
+
+// A tag is applied when its conditions match the rendered page.
+// Keywords are matched case-insensitively against the visible text,
+// selectors must match at least one element.
+type tagRule struct {
+	Keywords  []string `json:"keywords,omitempty"`
+	Selectors []string `json:"selectors,omitempty"`
+	// "any" (default) needs one condition to match, "all" needs every one
+	Match string `json:"match,omitempty"`
+}
+
+// Read a JSON object mapping tag names to rules
+func loadTagRules(path string) (map[string]tagRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string]tagRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid tag rules in %s: %v", path, err)
+	}
+	for name, rule := range rules {
+		if len(rule.Keywords) == 0 && len(rule.Selectors) == 0 {
+			return nil, fmt.Errorf("tag %q has no keywords or selectors", name)
+		}
+		if rule.Match != "" && rule.Match != "any" && rule.Match != "all" {
+			return nil, fmt.Errorf("tag %q: match must be \"any\" or \"all\"", name)
+		}
+	}
+	return rules, nil
+}
+
+// Evaluate every rule in the page and return the matched tags sorted
+func applyTagRules(ctx context.Context, rules map[string]tagRule) ([]string, error) {
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	javascript := fmt.Sprintf(`(() => {
+		const rules = %s;
+		const text = (document.body ? document.body.innerText : '').toLowerCase();
+		const matches = [];
+		for (const [tag, rule] of Object.entries(rules)) {
+			const results = [];
+			for (const kw of rule.keywords || []) {
+				results.push(text.includes(kw.toLowerCase()));
+			}
+			for (const sel of rule.selectors || []) {
+				let found = false;
+				try { found = document.querySelector(sel) !== null; } catch (e) {}
+				results.push(found);
+			}
+			const ok = rule.match === 'all' ? results.every(Boolean) : results.some(Boolean);
+			if (ok) matches.push(tag);
+		}
+		return matches;
+	})()`, rulesJSON)
+
+	var tags []string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &tags)); err != nil {
+		return nil, fmt.Errorf("error applying tag rules: %v", err)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
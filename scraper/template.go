@@ -0,0 +1,59 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Helpers available inside -template files
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Parse the -template file up front so mistakes show before scraping
+func loadOutputTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// Render the run result (URL, StatusCode, Title, Links, Metadata, Manifest) with the
+// template and save it as report.<ext>
+func writeTemplateOutput(out *outputDir, tmpl *template.Template, ext string, r *Result) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r); err != nil {
+		return "", fmt.Errorf("error executing template: %v", err)
+	}
+	name, err := templateFileName(ext)
+	if err != nil {
+		return "", err
+	}
+	return out.writeFile(name, buf.Bytes())
+}
+
+// report.<ext> for -template-ext. Letters and digits only, so the
+// extension can't add a path (-template-ext ../../x) to the file name.
+func templateFileName(ext string) (string, error) {
+	name := strings.TrimPrefix(ext, ".")
+	if name == "" || strings.IndexFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) >= 0 {
+		return "", fmt.Errorf("invalid template extension %q, expected letters and digits like md or csv", ext)
+	}
+	return "report." + name, nil
+}
@@ -0,0 +1,43 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateFileName(t *testing.T) {
+	tests := []struct {
+		ext, want string
+		wantErr   bool
+	}{
+		{"txt", "report.txt", false},
+		{".md", "report.md", false},
+		{"HTML5", "report.HTML5", false},
+		{"", "", true},
+		{".", "", true},
+		{"../../x", "", true},
+		{"x/y", "", true},
+		{`x\y`, "", true},
+		{"tar.gz", "", true},
+	}
+	for _, tt := range tests {
+		got, err := templateFileName(tt.ext)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("templateFileName(%q) = %q, %v, want %q (error %v)", tt.ext, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNewRejectsTemplateExtensionWithPath(t *testing.T) {
+	o := staticCrawlOptions(t)
+	o.Template = filepath.Join(t.TempDir(), "report.tmpl")
+	if err := os.WriteFile(o.Template, []byte("{{.URL}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o.TemplateExt = "../../x"
+	if s, err := New(o); err == nil {
+		s.Close()
+		t.Fatal("New accepted -template-ext ../../x")
+	}
+}
@@ -0,0 +1,169 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Timing of one finished (or failed) request
+type requestTiming struct {
+	URL        string  `json:"url"`
+	Type       string  `json:"type"`
+	DurationMS float64 `json:"duration_ms"`
+	ThirdParty bool    `json:"third_party"`
+	Failed     bool    `json:"failed,omitempty"`
+}
+
+type pendingRequest struct {
+	url     string
+	kind    network.ResourceType
+	started time.Time
+}
+
+// Measures every request of a tab from the time it was sent until it
+// finished loading or failed
+type requestTimer struct {
+	pageHost string
+
+	mu       sync.Mutex
+	pending  map[network.RequestID]pendingRequest
+	finished []requestTiming
+}
+
+func watchRequestTimings(ctx context.Context, pageHost string) *requestTimer {
+	rt := &requestTimer{pageHost: pageHost, pending: make(map[network.RequestID]pendingRequest)}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		rt.mu.Lock()
+		defer rt.mu.Unlock()
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			// Redirects reuse the request ID, keep the first start time
+			if _, ok := rt.pending[ev.RequestID]; !ok && ev.Timestamp != nil {
+				rt.pending[ev.RequestID] = pendingRequest{ev.Request.URL, ev.Type, ev.Timestamp.Time()}
+			}
+		case *network.EventLoadingFinished:
+			if ev.Timestamp != nil {
+				rt.finish(ev.RequestID, ev.Timestamp.Time(), false)
+			}
+		case *network.EventLoadingFailed:
+			if ev.Timestamp != nil {
+				rt.finish(ev.RequestID, ev.Timestamp.Time(), true)
+			}
+		}
+	})
+	return rt
+}
+
+// Caller holds rt.mu
+func (rt *requestTimer) finish(id network.RequestID, at time.Time, failed bool) {
+	req, ok := rt.pending[id]
+	if !ok {
+		return
+	}
+	delete(rt.pending, id)
+
+	thirdParty := false
+	if u, err := url.Parse(req.url); err == nil && u.Hostname() != "" {
+		thirdParty = !sameSite(u.Hostname(), rt.pageHost)
+	}
+	rt.finished = append(rt.finished, requestTiming{
+		URL:        req.url,
+		Type:       string(req.kind),
+		DurationMS: float64(at.Sub(req.started).Microseconds()) / 1000,
+		ThirdParty: thirdParty,
+		Failed:     failed,
+	})
+}
+
+// The n slowest requests, slowest first
+func (rt *requestTimer) slowest(n int) []requestTiming {
+	rt.mu.Lock()
+	timings := append([]requestTiming(nil), rt.finished...)
+	rt.mu.Unlock()
+
+	sort.Slice(timings, func(i, j int) bool { return timings[i].DurationMS > timings[j].DurationMS })
+	if len(timings) > n {
+		timings = timings[:n]
+	}
+	return timings
+}
+
+// Hosts are treated as the same site when one is a subdomain of the other
+func sameSite(host, pageHost string) bool {
+	return host == pageHost || hostAllowed(host, []string{"*." + pageHost}) || hostAllowed(pageHost, []string{"*." + host})
+}
+
+// Navigation milestones in ms since the navigation started. Browsers leave
+// some of them out (no paint entries for a page that never painted, no
+// Navigation Timing Level 2 in old engines), those stay nil.
+type loadTiming struct {
+	TTFBMS                 *float64 `json:"ttfb_ms,omitempty"`
+	FirstPaintMS           *float64 `json:"first_paint_ms,omitempty"`
+	FirstContentfulPaintMS *float64 `json:"first_contentful_paint_ms,omitempty"`
+	DOMContentLoadedMS     *float64 `json:"dom_content_loaded_ms,omitempty"`
+	LoadMS                 *float64 `json:"load_ms,omitempty"`
+}
+
+func extractLoadTiming(ctx context.Context) (*loadTiming, error) {
+	// performance.timing is the deprecated fallback, in epoch ms
+	javascript := `(() => {
+		const ms = (v) => (typeof v === 'number' && v > 0) ? Math.round(v * 10) / 10 : null;
+		const paint = (name) => {
+			const e = performance.getEntriesByName(name, 'paint')[0];
+			return e ? ms(e.startTime) : null;
+		};
+		const result = {
+			first_paint_ms: paint('first-paint'),
+			first_contentful_paint_ms: paint('first-contentful-paint'),
+		};
+		const nav = performance.getEntriesByType && performance.getEntriesByType('navigation')[0];
+		if (nav) {
+			result.ttfb_ms = ms(nav.responseStart);
+			result.dom_content_loaded_ms = ms(nav.domContentLoadedEventEnd);
+			result.load_ms = ms(nav.loadEventEnd);
+		} else if (performance.timing) {
+			const t = performance.timing, since = (v) => v > 0 ? ms(v - t.navigationStart) : null;
+			result.ttfb_ms = since(t.responseStart);
+			result.dom_content_loaded_ms = since(t.domContentLoadedEventEnd);
+			result.load_ms = since(t.loadEventEnd);
+		}
+		return result;
+	})()`
+	var timing loadTiming
+	if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &timing)); err != nil {
+		return nil, fmt.Errorf("error reading load timing: %v", err)
+	}
+	return &timing, nil
+}
+
+// "TTFB 120ms, FCP 340ms, DOMContentLoaded 410ms, load 980ms", missing
+// milestones are left out
+func (t *loadTiming) String() string {
+	var parts []string
+	for _, m := range []struct {
+		name  string
+		value *float64
+	}{
+		{"TTFB", t.TTFBMS},
+		{"first paint", t.FirstPaintMS},
+		{"FCP", t.FirstContentfulPaintMS},
+		{"DOMContentLoaded", t.DOMContentLoadedMS},
+		{"load", t.LoadMS},
+	} {
+		if m.value != nil {
+			parts = append(parts, fmt.Sprintf("%s %.0fms", m.name, *m.value))
+		}
+	}
+	if len(parts) == 0 {
+		return "not reported by the page"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// How often the page is polled while waiting
+const waitPollInterval = 250 * time.Millisecond
+
+// waitGone polls until no visible element matches selector. It returns an
+// error when the timeout passes first so the caller can log and carry on.
+func waitGone(ctx context.Context, selector string, timeout time.Duration) error {
+	selectorJSON, _ := json.Marshal(selector)
+	javascript := fmt.Sprintf(`Array.from(document.querySelectorAll(%s)).filter(el => {
+		const style = getComputedStyle(el);
+		return el.getClientRects().length > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	}).length`, selectorJSON)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var visible int
+		if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &visible)); err != nil {
+			return fmt.Errorf("error checking %q: %v", selector, err)
+		}
+		if visible == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d element(s) matching %q still visible after %s", visible, selector, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// Parse a -wait-count value, selector:N. The split is on the last colon so
+// selectors with pseudo classes still work.
+func ParseWaitCount(value string) (string, int, error) {
+	i := strings.LastIndex(value, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid -wait-count %q, expected selector:N", value)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value[i+1:]))
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("invalid -wait-count %q, N must be a positive number", value)
+	}
+	return strings.TrimSpace(value[:i]), n, nil
+}
+
+// waitCount polls until at least n elements match selector, for lists that
+// fill in incrementally. On timeout the error carries the count reached.
+func waitCount(ctx context.Context, selector string, n int, timeout time.Duration) error {
+	selectorJSON, _ := json.Marshal(selector)
+	javascript := fmt.Sprintf(`document.querySelectorAll(%s).length`, selectorJSON)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var count int
+		if err := chromedp.Run(ctx, chromedp.Evaluate(javascript, &count)); err != nil {
+			return fmt.Errorf("error counting %q: %v", selector, err)
+		}
+		if count >= n {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("only %d of %d element(s) matching %q after %s", count, n, selector, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// waitVisible blocks until selector is visible. Unlike the other waits a
+// timeout is an error, the page would only be an empty shell.
+func waitVisible(ctx context.Context, selector string, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := chromedp.Run(waitCtx, chromedp.WaitVisible(selector, chromedp.ByQuery)); err != nil {
+		return fmt.Errorf("selector %q did not become visible within %s: %v", selector, timeout, err)
+	}
+	return nil
+}
+
+// Requests of a tab that were sent and haven't finished or failed yet
+type networkIdle struct {
+	maxInflight int
+
+	mu       sync.Mutex
+	inflight map[network.RequestID]bool
+	// When the count last dropped to maxInflight or below, zero while above
+	idleSince time.Time
+}
+
+// Start counting before navigating, or the first requests are missed
+func watchNetworkIdle(ctx context.Context, maxInflight int) *networkIdle {
+	n := &networkIdle{maxInflight: maxInflight, inflight: make(map[network.RequestID]bool), idleSince: time.Now()}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			// Redirects reuse the request ID, it stays one request
+			n.inflight[ev.RequestID] = true
+		case *network.EventLoadingFinished:
+			delete(n.inflight, ev.RequestID)
+		case *network.EventLoadingFailed:
+			delete(n.inflight, ev.RequestID)
+		default:
+			return
+		}
+		switch idle := len(n.inflight) <= n.maxInflight; {
+		case !idle:
+			n.idleSince = time.Time{}
+		case n.idleSince.IsZero():
+			n.idleSince = time.Now()
+		}
+	})
+	return n
+}
+
+// wait returns once at most maxInflight requests have been open for quiet,
+// like Puppeteer's networkidle0/2. Pages that poll or stream never settle,
+// so after timeout (and always within the page deadline) it gives up with
+// an error telling how busy the network still was.
+func (n *networkIdle) wait(ctx context.Context, quiet, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		n.mu.Lock()
+		since, open := n.idleSince, len(n.inflight)
+		n.mu.Unlock()
+		if !since.IsZero() && time.Since(since) >= quiet {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("network not idle after %s, %d requests still in flight", timeout, open)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(waitPollInterval, quiet)):
+		}
+	}
+}